@@ -0,0 +1,145 @@
+package zfs_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	zfs "github.com/bicomsystems/go-libzfs"
+)
+
+func TestDataset_Bookmark(t *testing.T) {
+	zpoolTestPoolCreate(t)
+	defer func() {
+		zpoolTestPoolDestroy(t)
+		cleanupVDisks()
+	}()
+	println("TEST Dataset Bookmark ... ")
+	props := make(map[zfs.Prop]zfs.Property)
+	fspath := TSTPoolName + "/bookmarked"
+
+	d, err := zfs.DatasetCreate(fspath, zfs.DatasetTypeFilesystem, props)
+	if err != nil {
+		t.Errorf("DatasetCreate(\"%s\") error: %v", fspath, err)
+		return
+	}
+	d.Close()
+	s1, err := zfs.DatasetSnapshot(fspath+"@snap1", false, props)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	bm, err := s1.Bookmark("bm1")
+	if err != nil {
+		t.Error(err)
+		s1.Close()
+		return
+	}
+	bm.Close()
+	if err = s1.Destroy(false); err != nil {
+		t.Error(err)
+		s1.Close()
+		return
+	}
+	s1.Close()
+
+	s2, err := zfs.DatasetSnapshot(fspath+"@snap2", false, props)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer s2.Close()
+
+	if d, err = zfs.DatasetOpen(fspath); err != nil {
+		t.Error(err)
+		return
+	}
+	defer d.Close()
+	var bookmarks []zfs.Dataset
+	if bookmarks, err = d.Bookmarks(); err != nil {
+		t.Error(err)
+		return
+	}
+	if len(bookmarks) != 1 || !bookmarks[0].IsBookmark() ||
+		bookmarks[0].Properties[zfs.DatasetPropName].Value != fspath+"#bm1" {
+		t.Errorf("Bookmark %s#bm1 not found in %v", fspath, bookmarks)
+		return
+	}
+
+	// incremental send from bookmark of destroyed snapshot
+	var f *os.File
+	if f, err = ioutil.TempFile("", "zfs_send_"); err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	if err = s2.SendFrom("#bm1", f, zfs.SendFlags{}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = bookmarks[0].Destroy(false); err != nil {
+		t.Error(err)
+		return
+	}
+	print("PASS\n\n")
+}
+
+func TestDatasetDestroyBookmarked(t *testing.T) {
+	zpoolTestPoolCreate(t)
+	defer func() {
+		zpoolTestPoolDestroy(t)
+		cleanupVDisks()
+	}()
+	println("TEST Destroy dataset with bookmark ... ")
+	props := make(map[zfs.Prop]zfs.Property)
+	fspath := TSTPoolName + "/bookmarked"
+
+	d, err := zfs.DatasetCreate(fspath, zfs.DatasetTypeFilesystem, props)
+	if err != nil {
+		t.Errorf("DatasetCreate(\"%s\") error: %v", fspath, err)
+		return
+	}
+	d.Close()
+	s, err := zfs.DatasetSnapshot(fspath+"@snap", false, props)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	bm, err := s.Bookmark("bm")
+	if err != nil {
+		t.Error(err)
+		s.Close()
+		return
+	}
+	bm.Close()
+	err = s.Destroy(false)
+	s.Close()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// only child left is the bookmark
+	if d, err = zfs.DatasetOpen(fspath); err != nil {
+		t.Error(err)
+		return
+	}
+	if len(d.Children) != 1 || !d.Children[0].IsBookmark() {
+		t.Errorf("Expected only bookmark child of %s, got %v", fspath,
+			d.Children)
+	}
+	err = d.Destroy(false)
+	d.Close()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if d, err = zfs.DatasetOpen(fspath); err == nil {
+		d.Close()
+		t.Errorf("Dataset %s should be destroyed", fspath)
+		return
+	}
+	print("PASS\n\n")
+}
@@ -10,7 +10,7 @@ package zfs
 
 /*
 #cgo CFLAGS: -I /usr/include/libzfs -I /usr/include/libspl -DHAVE_IOCTL_IN_SYS_IOCTL_H -D_GNU_SOURCE
-#cgo LDFLAGS: -lzfs -lzpool -lnvpair
+#cgo LDFLAGS: -lzfs -lzfs_core -lzpool -lnvpair
 
 #include <stdlib.h>
 #include <libzfs.h>
@@ -22,12 +22,19 @@ import "C"
 
 import (
 	"errors"
+	"strings"
 	"sync"
 )
 
 // VDevType type of device in the pool
 type VDevType string
 
+// VDevAllocBias allocation class of top level device in the pool
+type VDevAllocBias string
+
+// VDevWholeDisk controls whether leaf disk device is whole disk or partition
+type VDevWholeDisk int
+
 func init() {
 	C.go_libzfs_init()
 	return
@@ -35,17 +42,34 @@ func init() {
 
 // Types of Virtual Devices
 const (
-	VDevTypeRoot      VDevType = "root"      // VDevTypeRoot root device in ZFS pool
-	VDevTypeMirror             = "mirror"    // VDevTypeMirror mirror device in ZFS pool
-	VDevTypeReplacing          = "replacing" // VDevTypeReplacing replacing
-	VDevTypeRaidz              = "raidz"     // VDevTypeRaidz RAIDZ device
-	VDevTypeDisk               = "disk"      // VDevTypeDisk device is disk
-	VDevTypeFile               = "file"      // VDevTypeFile device is file
-	VDevTypeMissing            = "missing"   // VDevTypeMissing missing device
-	VDevTypeHole               = "hole"      // VDevTypeHole hole
-	VDevTypeSpare              = "spare"     // VDevTypeSpare spare device
-	VDevTypeLog                = "log"       // VDevTypeLog ZIL device
-	VDevTypeL2cache            = "l2cache"   // VDevTypeL2cache cache device (disk)
+	VDevTypeRoot       VDevType = "root"      // VDevTypeRoot root device in ZFS pool
+	VDevTypeMirror              = "mirror"    // VDevTypeMirror mirror device in ZFS pool
+	VDevTypeReplacing           = "replacing" // VDevTypeReplacing replacing
+	VDevTypeRaidz               = "raidz"     // VDevTypeRaidz RAIDZ device
+	VDevTypeDisk                = "disk"      // VDevTypeDisk device is disk
+	VDevTypeFile                = "file"      // VDevTypeFile device is file
+	VDevTypeMissing             = "missing"   // VDevTypeMissing missing device
+	VDevTypeHole                = "hole"      // VDevTypeHole hole
+	VDevTypeSpare               = "spare"     // VDevTypeSpare spare device
+	VDevTypeLog                 = "log"       // VDevTypeLog ZIL device
+	VDevTypeL2cache             = "l2cache"   // VDevTypeL2cache cache device (disk)
+	VDevTypeDraid               = "draid"     // VDevTypeDraid dRAID device
+	VDevTypeDraidSpare          = "dspare"    // VDevTypeDraidSpare dRAID distributed spare
+)
+
+// Allocation classes of top level devices
+const (
+	VDevAllocBiasNone    VDevAllocBias = ""        // VDevAllocBiasNone normal data device
+	VDevAllocBiasLog                   = "log"     // VDevAllocBiasLog ZIL device
+	VDevAllocBiasSpecial               = "special" // VDevAllocBiasSpecial metadata and small blocks device
+	VDevAllocBiasDedup                 = "dedup"   // VDevAllocBiasDedup dedup table device
+)
+
+// Whole disk settings of leaf devices
+const (
+	VDevWholeDiskAuto VDevWholeDisk = iota // VDevWholeDiskAuto detect whole disk from device
+	VDevWholeDiskOn                        // VDevWholeDiskOn device is whole disk
+	VDevWholeDiskOff                       // VDevWholeDiskOff device is partition or file
 )
 
 // Prop type to enumerate all different properties suppoerted by ZFS
@@ -67,6 +91,68 @@ type VDevAux uint64
 type Property struct {
 	Value  string
 	Source string
+	// InheritedFrom name of dataset inherited dataset property comes from
+	InheritedFrom string
+}
+
+// PropertySource where property value comes from, parsed from Source of
+// Property
+type PropertySource int
+
+// Property sources
+const (
+	PropSourceNone      PropertySource = iota // read only property
+	PropSourceDefault                         // default value
+	PropSourceLocal                           // set on dataset or pool
+	PropSourceInherited                       // inherited from parent dataset
+	PropSourceReceived                        // received with zfs receive
+	PropSourceTemporary                       // temporary mount option
+)
+
+const propSourceInheritedFrom = "inherited from "
+
+// ParseSource parses Source of property, from is name of dataset property
+// is inherited from, if known
+func (p Property) ParseSource() (source PropertySource, from string) {
+	from = p.InheritedFrom
+	switch {
+	case p.Source == "none" || p.Source == "-" || len(p.Source) == 0:
+		source = PropSourceNone
+	case p.Source == "default":
+		source = PropSourceDefault
+	case p.Source == "local":
+		source = PropSourceLocal
+	case p.Source == "received":
+		source = PropSourceReceived
+	case p.Source == "temporary":
+		source = PropSourceTemporary
+	case p.Source == "inherited":
+		source = PropSourceInherited
+	case strings.HasPrefix(p.Source, propSourceInheritedFrom):
+		source = PropSourceInherited
+		from = p.Source[len(propSourceInheritedFrom):]
+	default:
+		// user properties have name of dataset they are inherited from
+		source = PropSourceInherited
+		from = p.Source
+	}
+	return
+}
+
+func (s PropertySource) String() string {
+	switch s {
+	case PropSourceDefault:
+		return "default"
+	case PropSourceLocal:
+		return "local"
+	case PropSourceInherited:
+		return "inherited"
+	case PropSourceReceived:
+		return "received"
+	case PropSourceTemporary:
+		return "temporary"
+	}
+	return "none"
 }
 
 var Global struct {
@@ -417,22 +503,27 @@ const (
 // vdev aux states.  When a vdev is in the VDevStateCantOpen state, the aux field
 // of the vdev stats structure uses these constants to distinguish why.
 const (
-	VDevAuxNone         VDevAux = iota // no error
-	VDevAuxOpenFailed                  // ldi_open_*() or vn_open() failed
-	VDevAuxCorruptData                 // bad label or disk contents
-	VDevAuxNoReplicas                  // insufficient number of replicas
-	VDevAuxBadGUIDSum                  // vdev guid sum doesn't match
-	VDevAuxTooSmall                    // vdev size is too small
-	VDevAuxBadLabel                    // the label is OK but invalid
-	VDevAuxVersionNewer                // on-disk version is too new
-	VDevAuxVersionOlder                // on-disk version is too old
-	VDevAuxUnsupFeat                   // unsupported features
-	VDevAuxSpared                      // hot spare used in another pool
-	VDevAuxErrExceeded                 // too many errors
-	VDevAuxIOFailure                   // experienced I/O failure
-	VDevAuxBadLog                      // cannot read log chain(s)
-	VDevAuxExternal                    // external diagnosis
-	VDevAuxSplitPool                   // vdev was split off into another pool
+	VDevAuxNone            VDevAux = iota // no error
+	VDevAuxOpenFailed                     // ldi_open_*() or vn_open() failed
+	VDevAuxCorruptData                    // bad label or disk contents
+	VDevAuxNoReplicas                     // insufficient number of replicas
+	VDevAuxBadGUIDSum                     // vdev guid sum doesn't match
+	VDevAuxTooSmall                       // vdev size is too small
+	VDevAuxBadLabel                       // the label is OK but invalid
+	VDevAuxVersionNewer                   // on-disk version is too new
+	VDevAuxVersionOlder                   // on-disk version is too old
+	VDevAuxUnsupFeat                      // unsupported features
+	VDevAuxSpared                         // hot spare used in another pool
+	VDevAuxErrExceeded                    // too many errors
+	VDevAuxIOFailure                      // experienced I/O failure
+	VDevAuxBadLog                         // cannot read log chain(s)
+	VDevAuxExternal                       // external diagnosis
+	VDevAuxSplitPool                      // vdev was split off into another pool
+	VDevAuxBadAshift                      // vdev ashift is invalid
+	VDevAuxExternalPersist                // external diagnosis, persistent across imports
+	VDevAuxActive                         // vdev active on a different host
+	VDevAuxChildrenOffline                // all children are offline
+	VDevAuxAshiftTooBig                   // vdev's min block size is too large
 )
 
 // status strings used by the zfs CLI when reporting zpool status.
@@ -13,6 +13,7 @@
 typedef struct property_list {
 	char value[INT_MAX_VALUE];
 	char source[ZFS_MAX_DATASET_NAME_LEN];
+	char origin[ZFS_MAX_DATASET_NAME_LEN];
 	int property;
 	void *pnext;
 } property_list_t;
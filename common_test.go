@@ -0,0 +1,38 @@
+package zfs_test
+
+import (
+	"testing"
+
+	zfs "github.com/bicomsystems/go-libzfs"
+)
+
+func TestPropertyParseSource(t *testing.T) {
+	tests := []struct {
+		source string
+		want   zfs.PropertySource
+		from   string
+	}{
+		{"none", zfs.PropSourceNone, ""},
+		{"-", zfs.PropSourceNone, ""},
+		{"default", zfs.PropSourceDefault, ""},
+		{"local", zfs.PropSourceLocal, ""},
+		{"received", zfs.PropSourceReceived, ""},
+		{"temporary", zfs.PropSourceTemporary, ""},
+		{"inherited", zfs.PropSourceInherited, ""},
+		{"inherited from pool/fs", zfs.PropSourceInherited, "pool/fs"},
+		{"pool/fs", zfs.PropSourceInherited, "pool/fs"},
+	}
+	for _, tt := range tests {
+		source, from := zfs.Property{Source: tt.source}.ParseSource()
+		if source != tt.want || from != tt.from {
+			t.Errorf("ParseSource(%q) = %s, %q, want %s, %q",
+				tt.source, source, from, tt.want, tt.from)
+		}
+	}
+	prop := zfs.Property{Source: "inherited", InheritedFrom: "pool/fs"}
+	if source, from := prop.ParseSource(); source != zfs.PropSourceInherited ||
+		from != "pool/fs" {
+		t.Errorf("ParseSource(%+v) = %s, %q, want inherited, \"pool/fs\"",
+			prop, source, from)
+	}
+}
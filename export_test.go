@@ -0,0 +1,9 @@
+package zfs
+
+// SetDevDiskDir sets directory used instead of /dev/disk, for tests of
+// persistent device names, returns function restoring it
+func SetDevDiskDir(dir string) (restore func()) {
+	prev := devDiskDir
+	devDiskDir = dir
+	return func() { devDiskDir = prev }
+}
@@ -0,0 +1,114 @@
+package zfs_test
+
+import (
+	"testing"
+
+	zfs "github.com/bicomsystems/go-libzfs"
+)
+
+func TestDataset_InheritProperty(t *testing.T) {
+	zpoolTestPoolCreate(t)
+	defer func() {
+		zpoolTestPoolDestroy(t)
+		cleanupVDisks()
+	}()
+	println("TEST Dataset InheritProperty ... ")
+	props := make(map[zfs.Prop]zfs.Property)
+	parentpath := TSTPoolName + "/parent"
+	childpath := parentpath + "/child"
+
+	p, err := zfs.DatasetCreate(parentpath, zfs.DatasetTypeFilesystem, props)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer p.Close()
+	c, err := zfs.DatasetCreate(childpath, zfs.DatasetTypeFilesystem, props)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer c.Close()
+
+	if err = p.SetProperty(zfs.DatasetPropCompression, "gzip"); err != nil {
+		t.Error(err)
+		return
+	}
+	if err = c.SetProperty(zfs.DatasetPropCompression, "off"); err != nil {
+		t.Error(err)
+		return
+	}
+	if err = c.SetUserProperty("go-libzfs:test", "child"); err != nil {
+		t.Error(err)
+		return
+	}
+	if err = p.SetUserProperty("go-libzfs:test", "parent"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = c.InheritProperty(zfs.DatasetPropCompression, false, false); err != nil {
+		t.Error(err)
+		return
+	}
+	prop := c.Properties[zfs.DatasetPropCompression]
+	if source, from := prop.ParseSource(); prop.Value != "gzip" ||
+		source != zfs.PropSourceInherited || from != parentpath {
+		t.Errorf("compression = %s (%s), expected gzip inherited from %s",
+			prop.Value, prop.Source, parentpath)
+		return
+	}
+	if prop.Source != "inherited" || prop.InheritedFrom != parentpath {
+		t.Errorf("compression source = %s from %s, expected inherited from %s",
+			prop.Source, prop.InheritedFrom, parentpath)
+		return
+	}
+
+	snappath := childpath + "@snap"
+	s, err := zfs.DatasetSnapshot(snappath, false, props)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = s.SetUserProperty("go-libzfs:test", "snapshot")
+	s.Close()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = p.InheritUserProperty("go-libzfs:test", true, false); err != nil {
+		t.Error(err)
+		return
+	}
+	// c is not open child of p, refresh its cached user properties
+	if err = c.ReloadProperties(); err != nil {
+		t.Error(err)
+		return
+	}
+	if prop, err = c.GetUserProperty("go-libzfs:test"); err != nil {
+		t.Error(err)
+		return
+	}
+	if source, _ := prop.ParseSource(); source != zfs.PropSourceNone {
+		t.Errorf("go-libzfs:test = %s (%s) after recursive inherit",
+			prop.Value, prop.Source)
+		return
+	}
+	// snapshots of descendants are inherited too
+	if s, err = zfs.DatasetOpen(snappath); err != nil {
+		t.Error(err)
+		return
+	}
+	defer s.Close()
+	if prop, err = s.GetUserProperty("go-libzfs:test"); err != nil {
+		t.Error(err)
+		return
+	}
+	if source, _ := prop.ParseSource(); source != zfs.PropSourceNone {
+		t.Errorf("%s go-libzfs:test = %s (%s) after recursive inherit",
+			snappath, prop.Value, prop.Source)
+		return
+	}
+	print("PASS\n\n")
+}
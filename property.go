@@ -0,0 +1,373 @@
+package zfs
+
+// #include <stdlib.h>
+// #include <libzfs.h>
+// #include "common.h"
+// #include "zpool.h"
+// #include "zfs.h"
+import "C"
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// PropType type of property value
+type PropType int
+
+// Property value types, see zprop_type_t
+const (
+	PropTypeNumber PropType = iota // numeric value, size, count or time
+	PropTypeString                 // any string
+	PropTypeIndex                  // one of Values
+)
+
+// PropInfo describes pool or dataset property
+type PropInfo struct {
+	Prop         Prop
+	Name         string
+	Pool         bool // pool property, otherwise dataset property
+	Type         PropType
+	ReadOnly     bool
+	SetOnce      bool // can be set only on creation
+	Inheritable  bool
+	DatasetTypes DatasetType // mask of dataset types property applies to
+	Values       []string    // valid values, as listed by zfs get, of index properties
+}
+
+var propRegistry struct {
+	once     sync.Once
+	datasets []PropInfo
+	pools    []PropInfo
+}
+
+func loadPropRegistry() {
+	for p := DatasetPropType; p < DatasetNumProps; p++ {
+		cprop := C.zfs_prop_t(p)
+		name := C.zfs_prop_to_name(cprop)
+		if name == nil {
+			continue
+		}
+		info := PropInfo{
+			Prop:        p,
+			Name:        C.GoString(name),
+			Type:        PropType(C.zfs_prop_get_type(cprop)),
+			ReadOnly:    C.zfs_prop_readonly(cprop) != C.B_FALSE,
+			SetOnce:     C.zfs_prop_setonce(cprop) != C.B_FALSE,
+			Inheritable: C.zfs_prop_inheritable(cprop) != C.B_FALSE,
+		}
+		for _, t := range []DatasetType{DatasetTypeFilesystem, DatasetTypeSnapshot,
+			DatasetTypeVolume, DatasetTypeBookmark} {
+			if C.zfs_prop_valid_for_type(C.int(p), C.zfs_type_t(t), C.B_FALSE) != C.B_FALSE {
+				info.DatasetTypes |= t
+			}
+		}
+		if info.Type == PropTypeIndex {
+			info.Values = splitPropValues(C.zfs_prop_values(cprop))
+		}
+		propRegistry.datasets = append(propRegistry.datasets, info)
+	}
+	for p := PoolPropName; p < PoolNumProps; p++ {
+		cprop := C.zpool_prop_t(p)
+		name := C.zpool_prop_to_name(cprop)
+		if name == nil {
+			continue
+		}
+		info := PropInfo{
+			Prop:     p,
+			Name:     C.GoString(name),
+			Pool:     true,
+			Type:     PropType(C.zpool_prop_get_type(cprop)),
+			ReadOnly: C.zpool_prop_readonly(cprop) != C.B_FALSE,
+			SetOnce:  C.zpool_prop_setonce(cprop) != C.B_FALSE,
+		}
+		if info.Type == PropTypeIndex {
+			info.Values = splitPropValues(C.zpool_prop_values(cprop))
+		}
+		propRegistry.pools = append(propRegistry.pools, info)
+	}
+}
+
+func splitPropValues(values *C.char) []string {
+	if values == nil {
+		return nil
+	}
+	return strings.Split(C.GoString(values), " | ")
+}
+
+// DatasetPropInfos returns description of all dataset properties
+func DatasetPropInfos() []PropInfo {
+	propRegistry.once.Do(loadPropRegistry)
+	return append([]PropInfo(nil), propRegistry.datasets...)
+}
+
+// PoolPropInfos returns description of all pool properties
+func PoolPropInfos() []PropInfo {
+	propRegistry.once.Do(loadPropRegistry)
+	return append([]PropInfo(nil), propRegistry.pools...)
+}
+
+// DatasetPropInfo returns description of dataset property
+func DatasetPropInfo(p Prop) (info PropInfo, err error) {
+	propRegistry.once.Do(loadPropRegistry)
+	for _, info = range propRegistry.datasets {
+		if info.Prop == p {
+			return
+		}
+	}
+	return PropInfo{}, fmt.Errorf("Unknown dataset property %d", p)
+}
+
+// PoolPropInfo returns description of pool property
+func PoolPropInfo(p Prop) (info PropInfo, err error) {
+	propRegistry.once.Do(loadPropRegistry)
+	for _, info = range propRegistry.pools {
+		if info.Prop == p {
+			return
+		}
+	}
+	return PropInfo{}, fmt.Errorf("Unknown pool property %d", p)
+}
+
+// Index returns numeric value of index property value, e.g. index of
+// compression algorithm
+func (info PropInfo) Index(value string) (index uint64, err error) {
+	if info.Type != PropTypeIndex {
+		err = fmt.Errorf("Property '%s' is not index property", info.Name)
+		return
+	}
+	csValue := C.CString(value)
+	defer C.free(unsafe.Pointer(csValue))
+	var cindex C.uint64_t
+	var r C.int
+	if info.Pool {
+		r = C.zpool_prop_string_to_index(C.zpool_prop_t(info.Prop), csValue, &cindex)
+	} else {
+		r = C.zfs_prop_string_to_index(C.zfs_prop_t(info.Prop), csValue, &cindex)
+	}
+	if r != 0 {
+		err = fmt.Errorf("Invalid value '%s' of property '%s'", value, info.Name)
+		return
+	}
+	index = uint64(cindex)
+	return
+}
+
+// indexString returns name of index property value, canonical form of
+// value accepted by Index
+func (info PropInfo) indexString(index uint64) (value string, err error) {
+	var cvalue *C.char
+	var r C.int
+	if info.Pool {
+		r = C.zpool_prop_index_to_string(C.zpool_prop_t(info.Prop), C.uint64_t(index), &cvalue)
+	} else {
+		r = C.zfs_prop_index_to_string(C.zfs_prop_t(info.Prop), C.uint64_t(index), &cvalue)
+	}
+	if r != 0 {
+		err = fmt.Errorf("Invalid index %d of property '%s'", index, info.Name)
+		return
+	}
+	value = C.GoString(cvalue)
+	return
+}
+
+// ValidateProperty validates value of dataset property for new dataset of
+// given type without touching ZFS, the way libzfs does it before creating
+// dataset. Returns value normalized the way ZFS stores it, e.g. sizes in
+// bytes, or error with reason why the value is invalid.
+func ValidateProperty(p Prop, value string, dtype DatasetType) (normalized string, err error) {
+	info, err := DatasetPropInfo(p)
+	if err != nil {
+		return
+	}
+	if info.DatasetTypes&dtype == 0 {
+		err = fmt.Errorf("Property '%s' does not apply to datasets of this type",
+			info.Name)
+		return
+	}
+	csValue := C.CString(value)
+	defer C.free(unsafe.Pointer(csValue))
+	cnormalized := (*C.char)(C.malloc(C.INT_MAX_VALUE))
+	if cnormalized == nil {
+		err = errors.New("Failed to allocate normalized value")
+		return
+	}
+	defer C.free(unsafe.Pointer(cnormalized))
+	Global.Mtx.Lock()
+	defer Global.Mtx.Unlock()
+	if C.validate_dataset_prop(C.zfs_type_t(dtype), C.zfs_prop_t(p), csValue,
+		cnormalized, C.INT_MAX_VALUE) != 0 {
+		err = fmt.Errorf("Invalid value '%s' of property '%s': %s", value,
+			info.Name, LastError().Error())
+		return
+	}
+	normalized = C.GoString(cnormalized)
+	return
+}
+
+// Pool property limits checked by zpool_valid_proplist
+const (
+	poolMinAshift     = C.ASHIFT_MIN
+	poolMaxAshift     = C.ASHIFT_MAX
+	poolMaxComment    = C.ZPROP_MAX_COMMENT
+	poolLegacyVersion = C.SPA_VERSION_BEFORE_FEATURES
+	poolFeatureVer    = C.SPA_VERSION_FEATURES
+)
+
+// ValidatePoolProperty validates value of pool property without touching
+// ZFS. Returns value normalized the way ZFS stores it, or error with reason
+// why the value is invalid.
+func ValidatePoolProperty(p Prop, value string) (normalized string, err error) {
+	info, err := PoolPropInfo(p)
+	if err != nil {
+		return
+	}
+	if info.ReadOnly {
+		err = fmt.Errorf("Property '%s' is readonly", info.Name)
+		return
+	}
+	switch info.Type {
+	case PropTypeIndex:
+		var index uint64
+		if index, err = info.Index(value); err != nil {
+			err = fmt.Errorf("Invalid value '%s' of property '%s': must be one of '%s'",
+				value, info.Name, strings.Join(info.Values, " | "))
+			return
+		}
+		normalized, err = info.indexString(index)
+	case PropTypeNumber:
+		var n uint64
+		if n, err = (Property{Value: value}).Uint64(); err != nil {
+			err = fmt.Errorf("Invalid value '%s' of property '%s': must be a number",
+				value, info.Name)
+			return
+		}
+		switch {
+		case p == PoolPropAshift && n != 0 && (n < poolMinAshift || n > poolMaxAshift):
+			err = fmt.Errorf("Invalid value '%s' of property '%s': must be 0 or in range %d-%d",
+				value, info.Name, poolMinAshift, poolMaxAshift)
+			return
+		case p == PoolPropVersion && (n < 1 || n > poolLegacyVersion) && n != poolFeatureVer:
+			err = fmt.Errorf("Invalid value '%s' of property '%s': unsupported version",
+				value, info.Name)
+			return
+		}
+		normalized = strconv.FormatUint(n, 10)
+	default:
+		switch p {
+		case PoolPropComment:
+			if len(value) > poolMaxComment {
+				err = fmt.Errorf("Invalid value of property '%s': must be at most %d characters",
+					info.Name, poolMaxComment)
+				return
+			}
+			for _, c := range value {
+				if c < ' ' || c > '~' {
+					err = fmt.Errorf("Invalid value of property '%s': must contain only printable ASCII",
+						info.Name)
+					return
+				}
+			}
+		case PoolPropAltroot, PoolPropCachefile:
+			if len(value) > 0 && value != "none" && !strings.HasPrefix(value, "/") {
+				err = fmt.Errorf("Invalid value '%s' of property '%s': must be an absolute path",
+					value, info.Name)
+				return
+			}
+		}
+		normalized = value
+	}
+	return
+}
+
+// Uint64 returns numeric property value. Properties are read in parsable
+// form, sizes in bytes, but human readable sizes like 1.5G are accepted
+// too. Value none (no quota, no limit) is 0.
+func (p Property) Uint64() (v uint64, err error) {
+	if p.Value == "none" {
+		return
+	}
+	if v, err = strconv.ParseUint(p.Value, 10, 64); err == nil {
+		return
+	}
+	return parseNiceNum(p.Value)
+}
+
+// Bool returns value of on/off or yes/no property
+func (p Property) Bool() (v bool, err error) {
+	switch p.Value {
+	case "on", "yes", "true", "1":
+		v = true
+	case "off", "no", "false", "0":
+	default:
+		err = fmt.Errorf("Invalid boolean property value '%s'", p.Value)
+	}
+	return
+}
+
+// Time returns value of time property (e.g. creation), parsable form of
+// which is seconds since epoch
+func (p Property) Time() (t time.Time, err error) {
+	sec, err := strconv.ParseInt(p.Value, 10, 64)
+	if err != nil {
+		err = fmt.Errorf("Invalid time property value '%s'", p.Value)
+		return
+	}
+	t = time.Unix(sec, 0)
+	return
+}
+
+// Ratio returns value of ratio property (e.g. compressratio), as 1.50 or
+// 1.50x
+func (p Property) Ratio() (r float64, err error) {
+	if r, err = strconv.ParseFloat(strings.TrimSuffix(p.Value, "x"), 64); err != nil {
+		err = fmt.Errorf("Invalid ratio property value '%s'", p.Value)
+	}
+	return
+}
+
+// Enum returns numeric value of index property described by info
+func (p Property) Enum(info PropInfo) (index uint64, err error) {
+	return info.Index(p.Value)
+}
+
+// parseNiceNum parses human readable number, digits with optional
+// fraction, and optional binary suffix (K, M, G, T, P, E, optionally
+// followed by B), like zfs_nicestrtonum
+func parseNiceNum(s string) (v uint64, err error) {
+	digits := func(i int) int {
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		return i
+	}
+	end := digits(0)
+	if end < len(s) && s[end] == '.' {
+		end = digits(end + 1)
+	}
+	num, suffix := s[:end], strings.ToUpper(s[end:])
+	if len(suffix) > 1 {
+		suffix = strings.TrimSuffix(suffix, "B")
+	}
+	var shift uint
+	if len(suffix) > 0 && suffix != "B" {
+		i := strings.Index("KMGTPE", suffix)
+		if len(suffix) > 1 || i < 0 {
+			err = fmt.Errorf("Invalid numeric property value '%s'", s)
+			return
+		}
+		shift = uint(i+1) * 10
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil || f*float64(uint64(1)<<shift) >= math.MaxUint64 {
+		err = fmt.Errorf("Invalid numeric property value '%s'", s)
+		return
+	}
+	v = uint64(f * float64(uint64(1)<<shift))
+	return
+}
@@ -0,0 +1,254 @@
+package zfs_test
+
+import (
+	"testing"
+	"time"
+
+	zfs "github.com/bicomsystems/go-libzfs"
+)
+
+func TestDataset_SetProperties(t *testing.T) {
+	zpoolTestPoolCreate(t)
+	defer func() {
+		zpoolTestPoolDestroy(t)
+		cleanupVDisks()
+	}()
+	println("TEST Dataset SetProperties ... ")
+	dspath := TSTPoolName + "/properties"
+	d, err := zfs.DatasetCreate(dspath, zfs.DatasetTypeFilesystem,
+		make(map[zfs.Prop]zfs.Property))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer d.Close()
+
+	// nothing is set if any of the values is invalid
+	err = d.SetProperties(map[zfs.Prop]string{
+		zfs.DatasetPropCompression: "lz4",
+		zfs.DatasetPropRecordsize:  "3k",
+		zfs.DatasetPropQuota:       "invalid",
+	}, nil)
+	perrs, ok := err.(zfs.PropertyErrors)
+	if !ok || len(perrs) != 2 {
+		t.Errorf("SetProperties with invalid values returned: %v", err)
+		return
+	}
+	if d.Properties[zfs.DatasetPropCompression].Value == "lz4" {
+		t.Error("compression set while other properties are invalid")
+		return
+	}
+
+	err = d.SetProperties(map[zfs.Prop]string{
+		zfs.DatasetPropCompression: "lz4",
+		zfs.DatasetPropRecordsize:  "64K",
+		zfs.DatasetPropQuota:       "100M",
+		zfs.DatasetPropReservation: "10M",
+	}, map[string]string{"go-libzfs:profile": "test"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if d.Properties[zfs.DatasetPropCompression].Value != "lz4" ||
+		d.Properties[zfs.DatasetPropRecordsize].Value != "65536" {
+		t.Errorf("Properties not set: compression=%s recordsize=%s",
+			d.Properties[zfs.DatasetPropCompression].Value,
+			d.Properties[zfs.DatasetPropRecordsize].Value)
+		return
+	}
+	var prop zfs.Property
+	if prop, err = d.GetUserProperty("go-libzfs:profile"); err != nil || prop.Value != "test" {
+		t.Errorf("go-libzfs:profile = %s, %v", prop.Value, err)
+		return
+	}
+	print("PASS\n\n")
+}
+
+func TestPropertyTypedValues(t *testing.T) {
+	sizes := map[string]uint64{
+		"0":        0,
+		"none":     0,
+		"65536":    65536,
+		"64K":      65536,
+		"1.5G":     3 << 29,
+		"2TB":      2 << 40,
+		"512b":     512,
+		"0.5K":     512,
+		"1.K":      1024,
+		"10000000": 10000000,
+	}
+	for value, want := range sizes {
+		if v, err := (zfs.Property{Value: value}).Uint64(); err != nil || v != want {
+			t.Errorf("Uint64(%q) = %d, %v, want %d", value, v, err, want)
+		}
+	}
+	for _, value := range []string{"", "-", ".", "K", "1X", "1KK", "-5", "+5",
+		"nan", "NaN", "inf", "Inf", "1e5", "1e5K", "0x10", "1.5.5", " 1K"} {
+		if v, err := (zfs.Property{Value: value}).Uint64(); err == nil {
+			t.Errorf("Uint64(%q) = %d, expected error", value, v)
+		}
+	}
+
+	bools := map[string]bool{"on": true, "yes": true, "off": false, "no": false}
+	for value, want := range bools {
+		if v, err := (zfs.Property{Value: value}).Bool(); err != nil || v != want {
+			t.Errorf("Bool(%q) = %t, %v, want %t", value, v, err, want)
+		}
+	}
+	if _, err := (zfs.Property{Value: "-"}).Bool(); err == nil {
+		t.Error("Bool(\"-\") expected error")
+	}
+
+	if tm, err := (zfs.Property{Value: "1600000000"}).Time(); err != nil ||
+		!tm.Equal(time.Unix(1600000000, 0)) {
+		t.Errorf("Time() = %v, %v", tm, err)
+	}
+
+	for _, value := range []string{"1.50", "1.50x"} {
+		if r, err := (zfs.Property{Value: value}).Ratio(); err != nil || r != 1.5 {
+			t.Errorf("Ratio(%q) = %f, %v", value, r, err)
+		}
+	}
+}
+
+func TestPropInfo(t *testing.T) {
+	println("TEST PropInfo ... ")
+	info, err := zfs.DatasetPropInfo(zfs.DatasetPropCompression)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if info.Name != "compression" || info.Type != zfs.PropTypeIndex ||
+		info.ReadOnly || !info.Inheritable ||
+		info.DatasetTypes&zfs.DatasetTypeFilesystem == 0 ||
+		info.DatasetTypes&zfs.DatasetTypeSnapshot != 0 || len(info.Values) == 0 {
+		t.Errorf("Unexpected compression info: %+v", info)
+		return
+	}
+	if _, err = (zfs.Property{Value: "lz4"}).Enum(info); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err = (zfs.Property{Value: "invalid"}).Enum(info); err == nil {
+		t.Error("Enum of invalid compression value succeeded")
+		return
+	}
+	if info, err = zfs.DatasetPropInfo(zfs.DatasetPropUsed); err != nil {
+		t.Error(err)
+		return
+	}
+	if info.Type != zfs.PropTypeNumber || !info.ReadOnly {
+		t.Errorf("Unexpected used info: %+v", info)
+		return
+	}
+	if info, err = zfs.PoolPropInfo(zfs.PoolPropAshift); err != nil {
+		t.Error(err)
+		return
+	}
+	if !info.Pool || info.Name != "ashift" {
+		t.Errorf("Unexpected ashift info: %+v", info)
+		return
+	}
+	print("PASS\n\n")
+}
+
+func TestValidateProperty(t *testing.T) {
+	println("TEST ValidateProperty ... ")
+	valid := []struct {
+		prop       zfs.Prop
+		value      string
+		dtype      zfs.DatasetType
+		normalized string
+	}{
+		{zfs.DatasetPropQuota, "10G", zfs.DatasetTypeFilesystem, "10737418240"},
+		{zfs.DatasetPropCompression, "lz4", zfs.DatasetTypeVolume, "lz4"},
+		{zfs.DatasetPropAtime, "off", zfs.DatasetTypeFilesystem, "off"},
+	}
+	for _, tt := range valid {
+		normalized, err := zfs.ValidateProperty(tt.prop, tt.value, tt.dtype)
+		if err != nil || normalized != tt.normalized {
+			t.Errorf("ValidateProperty(%s, %q) = %q, %v, want %q",
+				zfs.DatasetPropertyToName(tt.prop), tt.value, normalized, err,
+				tt.normalized)
+		}
+	}
+	invalid := []struct {
+		prop  zfs.Prop
+		value string
+		dtype zfs.DatasetType
+	}{
+		{zfs.DatasetPropCompression, "lz5", zfs.DatasetTypeFilesystem},
+		{zfs.DatasetPropQuota, "10QB", zfs.DatasetTypeFilesystem},
+		{zfs.DatasetPropRecordsize, "128K", zfs.DatasetTypeVolume},
+		{zfs.DatasetPropUsed, "1G", zfs.DatasetTypeFilesystem},
+	}
+	for _, tt := range invalid {
+		if _, err := zfs.ValidateProperty(tt.prop, tt.value, tt.dtype); err == nil {
+			t.Errorf("ValidateProperty(%s, %q) expected error",
+				zfs.DatasetPropertyToName(tt.prop), tt.value)
+		}
+	}
+
+	if normalized, err := zfs.ValidatePoolProperty(zfs.PoolPropAshift, "12"); err != nil ||
+		normalized != "12" {
+		t.Errorf("ValidatePoolProperty(ashift, 12) = %q, %v", normalized, err)
+	}
+	for prop, value := range map[zfs.Prop]string{
+		zfs.PoolPropAshift:      "20",
+		zfs.PoolPropFailuremode: "ignore",
+		zfs.PoolPropSize:        "1G",
+	} {
+		if _, err := zfs.ValidatePoolProperty(prop, value); err == nil {
+			t.Errorf("ValidatePoolProperty(%d, %q) expected error", prop, value)
+		}
+	}
+	print("PASS\n\n")
+}
+
+func TestDatasetOpenWithOptions(t *testing.T) {
+	zpoolTestPoolCreate(t)
+	defer func() {
+		zpoolTestPoolDestroy(t)
+		cleanupVDisks()
+	}()
+	println("TEST DatasetOpenWithOptions ... ")
+	props := make(map[zfs.Prop]zfs.Property)
+	dspath := TSTPoolName + "/selective"
+	d, err := zfs.DatasetCreate(dspath, zfs.DatasetTypeFilesystem, props)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	d.Close()
+	s, err := zfs.DatasetSnapshot(dspath+"@snap", false, props)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	s.Close()
+
+	d, err = zfs.DatasetOpenWithOptions(dspath,
+		&zfs.DatasetOpenOptions{Props: []zfs.Prop{zfs.DatasetPropUsed}})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer d.Close()
+	if len(d.Children) != 1 || len(d.Children[0].Properties) != 2 {
+		t.Errorf("Expected one snapshot with name and used loaded, got %v", d.Children)
+		return
+	}
+	if _, ok := d.Properties[zfs.DatasetPropCompression]; ok {
+		t.Error("compression loaded although not requested")
+		return
+	}
+	if _, err = d.GetProperty(zfs.DatasetPropCompression); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, ok := d.Properties[zfs.DatasetPropCompression]; !ok {
+		t.Error("compression not loaded by GetProperty")
+		return
+	}
+	print("PASS\n\n")
+}
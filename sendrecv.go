@@ -9,6 +9,7 @@ package zfs
 // #include <string.h>
 import "C"
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -194,6 +195,10 @@ func (d *Dataset) Send(outf *os.File, flags SendFlags) (err error) {
 func (d *Dataset) SendFrom(FromName string, outf *os.File, flags SendFlags) (err error) {
 	var porigin Property
 	var from, dest []string
+	if strings.Contains(FromName, "#") {
+		// bookmarks can be sent from only by zfs_send_one
+		return d.SendOne(FromName, outf, flags)
+	}
 	if err = d.ReloadProperties(); err != nil {
 		return
 	}
@@ -222,6 +227,44 @@ func (d *Dataset) SendFrom(FromName string, outf *os.File, flags SendFlags) (err
 	return
 }
 
+// SendOne sends snapshot stream the way zfs_send_one does, without
+// recursion or properties. FromName is incremental source, snapshot or
+// bookmark of the same dataset, as full name or relative name ('@snap',
+// '#bookmark'). Leave FromName empty for full stream.
+func (d *Dataset) SendOne(FromName string, outf *os.File, flags SendFlags) (err error) {
+	var dpath string
+	var cfromname *C.char
+	if d.list == nil {
+		err = errors.New(msgDatasetIsNil)
+		return
+	}
+	if d.Type != DatasetTypeSnapshot {
+		err = fmt.Errorf("Unsupported method on filesystem or bookmark.")
+		return
+	}
+	if dpath, err = d.Path(); err != nil {
+		return
+	}
+	parent := strings.Split(dpath, "@")[0]
+	if len(FromName) > 0 {
+		if FromName[0] == '@' || FromName[0] == '#' {
+			FromName = parent + FromName
+		}
+		if i := strings.IndexAny(FromName, "@#"); i < 0 || FromName[:i] != parent {
+			err = fmt.Errorf("Incremental source must be in same filesystem.")
+			return
+		}
+		cfromname = C.CString(FromName)
+		defer C.free(unsafe.Pointer(cfromname))
+	}
+	cflags := to_sendflags_t(&flags)
+	defer C.free(unsafe.Pointer(cflags))
+	if cerr := C.dataset_send_one(d.list, cfromname, C.int(outf.Fd()), cflags); cerr != 0 {
+		err = LastError()
+	}
+	return
+}
+
 // SendSize - estimate snapshot size to transfer
 func (d *Dataset) SendSize(FromName string, flags SendFlags) (size int64, err error) {
 	var r, w *os.File
@@ -0,0 +1,425 @@
+package zfs_test
+
+import (
+	"crypto/rand"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	zfs "github.com/bicomsystems/go-libzfs"
+)
+
+func TestSnapshotMany(t *testing.T) {
+	zpoolTestPoolCreate(t)
+	defer func() {
+		zpoolTestPoolDestroy(t)
+		cleanupVDisks()
+	}()
+	println("TEST SnapshotMany ... ")
+	props := make(map[zfs.Prop]zfs.Property)
+	fs1, fs2 := TSTPoolName+"/app-data", TSTPoolName+"/app-logs"
+	for _, name := range []string{fs1, fs2} {
+		d, err := zfs.DatasetCreate(name, zfs.DatasetTypeFilesystem, props)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		d.Close()
+	}
+
+	snaps := []string{fs1 + "@consistent", fs2 + "@consistent"}
+	err := zfs.SnapshotMany(snaps, map[string]string{"go-libzfs:app": "test"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var txg string
+	for _, name := range snaps {
+		s, err := zfs.DatasetOpenSingle(name)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		prop, err := s.GetUserProperty("go-libzfs:app")
+		if err != nil || prop.Value != "test" {
+			t.Errorf("%s go-libzfs:app = %s, %v", name, prop.Value, err)
+		}
+		if len(txg) > 0 && s.Properties[zfs.DatasetPropCreateTXG].Value != txg {
+			t.Errorf("Snapshots created in different txgs")
+		}
+		txg = s.Properties[zfs.DatasetPropCreateTXG].Value
+		s.Close()
+	}
+
+	// snapshot of missing dataset fails, nothing is created
+	err = zfs.SnapshotMany([]string{fs1 + "@second", TSTPoolName + "/missing@second"}, nil)
+	serrs, ok := err.(zfs.SnapshotErrors)
+	if !ok || serrs[TSTPoolName+"/missing@second"] == nil {
+		t.Errorf("Expected error of missing dataset snapshot, got %v", err)
+		return
+	}
+	if s, err := zfs.DatasetOpenSingle(fs1 + "@second"); err == nil {
+		s.Close()
+		t.Error("Snapshot created although other snapshot failed")
+		return
+	}
+	print("PASS\n\n")
+}
+
+func TestDestroySnapshots(t *testing.T) {
+	zpoolTestPoolCreate(t)
+	defer func() {
+		zpoolTestPoolDestroy(t)
+		cleanupVDisks()
+	}()
+	println("TEST DestroySnapshots ... ")
+	mnt, err := ioutil.TempDir("", "zfs_range_")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(mnt)
+	props := make(map[zfs.Prop]zfs.Property)
+	fs, child := TSTPoolName+"/range", TSTPoolName+"/range/child"
+	d, err := zfs.DatasetCreate(fs, zfs.DatasetTypeFilesystem,
+		map[zfs.Prop]zfs.Property{zfs.DatasetPropMountpoint: {Value: mnt}})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = d.Mount("", 0)
+	d.Close()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer func() {
+		if d, err := zfs.DatasetOpenSingle(fs); err == nil {
+			d.Unmount(0)
+			d.Close()
+		}
+	}()
+	// every snapshot is the only one referencing its file
+	const fileSize = 1 << 20
+	data := make([]byte, fileSize)
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		file := filepath.Join(mnt, name)
+		rand.Read(data)
+		if err = ioutil.WriteFile(file, data, 0600); err != nil {
+			t.Error(err)
+			return
+		}
+		s, err := zfs.DatasetSnapshot(fs+"@"+name, false, props)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		s.Close()
+		if err = os.Remove(file); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	// range b%d is reversed in child
+	if d, err = zfs.DatasetCreate(child, zfs.DatasetTypeFilesystem, props); err != nil {
+		t.Error(err)
+		return
+	}
+	d.Close()
+	for _, name := range []string{"d", "b"} {
+		s, err := zfs.DatasetSnapshot(child+"@"+name, false, props)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		s.Close()
+	}
+
+	res, err := zfs.DestroySnapshots(fs, "b%d,missing",
+		&zfs.DestroySnapshotsOptions{DryRun: true, Recursive: true})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	expected := []string{fs + "@b", fs + "@c", fs + "@d"}
+	if strings.Join(res.Snapshots, " ") != strings.Join(expected, " ") {
+		t.Errorf("Dry run snapshots %v, expected %v", res.Snapshots, expected)
+		return
+	}
+	if len(res.Reclaimed) != len(expected) {
+		t.Errorf("Reclaimed space of %d snapshots, expected %d",
+			len(res.Reclaimed), len(expected))
+		return
+	}
+	var sum uint64
+	for _, name := range expected {
+		if res.Reclaimed[name] < fileSize || res.Reclaimed[name] > 2*fileSize {
+			t.Errorf("Reclaimed %d bytes by %s, expected about %d",
+				res.Reclaimed[name], name, fileSize)
+		}
+		sum += res.Reclaimed[name]
+	}
+	if res.Total < sum || res.Total > 2*uint64(len(expected))*fileSize {
+		t.Errorf("Total %d bytes reclaimed, expected at least %d and about %d",
+			res.Total, sum, len(expected)*fileSize)
+	}
+	s, err := zfs.DatasetOpenSingle(fs + "@c")
+	if err != nil {
+		t.Error("Snapshot destroyed in dry run")
+		return
+	}
+	s.Close()
+	if _, err = zfs.DestroySnapshots(child, "b%d", nil); err == nil {
+		t.Error("Expected error of reversed range")
+		return
+	}
+
+	if res, err = zfs.DestroySnapshots(fs, "%b,e", nil); err != nil {
+		t.Error(err)
+		return
+	}
+	d, err = zfs.DatasetOpen(fs)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer d.Close()
+	snaps, _ := d.Snapshots()
+	if len(snaps) != 2 {
+		t.Errorf("%d snapshots left, expected 2 (c, d)", len(snaps))
+		return
+	}
+	if _, err = zfs.DestroySnapshots(fs, "x%y", nil); err == nil {
+		t.Error("Expected error of spec matching no snapshots")
+		return
+	}
+	print("PASS\n\n")
+}
+
+func TestHoldRecursive(t *testing.T) {
+	zpoolTestPoolCreate(t)
+	defer func() {
+		zpoolTestPoolDestroy(t)
+		cleanupVDisks()
+	}()
+	println("TEST HoldRecursive ... ")
+	props := make(map[zfs.Prop]zfs.Property)
+	parent, child := TSTPoolName+"/repl", TSTPoolName+"/repl/child"
+	for _, name := range []string{parent, child} {
+		d, err := zfs.DatasetCreate(name, zfs.DatasetTypeFilesystem, props)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		d.Close()
+	}
+	s, err := zfs.DatasetSnapshot(parent+"@send", true, props)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer s.Close()
+	if err = s.HoldRecursive("replication", nil); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err = zfs.DestroySnapshots(child, "send", nil); err == nil {
+		t.Error("Held snapshot of child destroyed")
+		return
+	}
+	if err = s.ReleaseRecursive("replication"); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err = zfs.DestroySnapshots(parent, "send",
+		&zfs.DestroySnapshotsOptions{Recursive: true}); err != nil {
+		t.Error(err)
+		return
+	}
+	print("PASS\n\n")
+}
+
+func TestHoldSnapshots(t *testing.T) {
+	zpoolTestPoolCreate(t)
+	defer func() {
+		zpoolTestPoolDestroy(t)
+		cleanupVDisks()
+	}()
+	println("TEST HoldSnapshots ... ")
+	props := make(map[zfs.Prop]zfs.Property)
+	fs1, fs2 := TSTPoolName+"/hold1", TSTPoolName+"/hold2"
+	for _, name := range []string{fs1, fs2} {
+		d, err := zfs.DatasetCreate(name, zfs.DatasetTypeFilesystem, props)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		d.Close()
+	}
+	snaps := []string{fs1 + "@s", fs2 + "@s"}
+	if err := zfs.SnapshotMany(snaps, nil); err != nil {
+		t.Error(err)
+		return
+	}
+	holds := map[string]string{snaps[0]: "batch", snaps[1]: "batch"}
+	if err := zfs.HoldSnapshots(holds, nil); err != nil {
+		t.Error(err)
+		return
+	}
+	// tag already exists on both, none is added
+	err := zfs.HoldSnapshots(holds, nil)
+	if _, ok := err.(zfs.HoldErrors); !ok {
+		t.Errorf("Expected HoldErrors of existing holds, got %v", err)
+		return
+	}
+	err = zfs.ReleaseSnapshots(map[string][]string{
+		snaps[0]: {"batch"}, snaps[1]: {"batch"}})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// missing snapshot is reported, hold of the other is added
+	missing := fs2 + "@missing"
+	err = zfs.HoldSnapshots(map[string]string{snaps[0]: "some", missing: "some"}, nil)
+	if herrs, ok := err.(zfs.HoldErrors); !ok || len(herrs) != 1 ||
+		herrs[missing] != syscall.ENOENT {
+		t.Errorf("Expected HoldErrors of missing snapshot, got %v", err)
+		return
+	}
+	err = zfs.ReleaseSnapshots(map[string][]string{
+		snaps[0]: {"some"}, missing: {"some"}})
+	if rerrs, ok := err.(zfs.ReleaseErrors); !ok || len(rerrs) != 1 ||
+		rerrs[missing] != syscall.ENOENT {
+		t.Errorf("Expected ReleaseErrors of missing snapshot, got %v", err)
+		return
+	}
+
+	cleanup, err := zfs.HoldCleanupFile()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err = zfs.HoldSnapshots(map[string]string{snaps[0]: "temporary"}, cleanup); err != nil {
+		cleanup.Close()
+		t.Error(err)
+		return
+	}
+	if _, err = zfs.DestroySnapshots(fs1, "s", nil); err == nil {
+		t.Error("Held snapshot destroyed")
+		return
+	}
+	cleanup.Close()
+	if _, err = zfs.DestroySnapshots(fs1, "s", nil); err != nil {
+		t.Errorf("Hold not released on close of cleanup file: %v", err)
+		return
+	}
+	print("PASS\n\n")
+}
+
+func TestRollbackTo(t *testing.T) {
+	zpoolTestPoolCreate(t)
+	defer func() {
+		zpoolTestPoolDestroy(t)
+		cleanupVDisks()
+	}()
+	println("TEST RollbackTo ... ")
+	props := make(map[zfs.Prop]zfs.Property)
+	fs := TSTPoolName + "/rollback"
+	d, err := zfs.DatasetCreate(fs, zfs.DatasetTypeFilesystem, props)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer d.Close()
+	var snaps []zfs.Dataset
+	defer func() { zfs.DatasetCloseAll(snaps) }()
+	for _, name := range []string{"a", "b", "c"} {
+		s, err := zfs.DatasetSnapshot(fs+"@"+name, false, props)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		snaps = append(snaps, s)
+	}
+	bookmark, err := snaps[1].Bookmark(fs + "#b")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	bookmark.Close()
+	clone, err := snaps[2].Clone(TSTPoolName+"/rollback-clone", props)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	clone.Close()
+	if err = snaps[1].Hold("keep"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	plan, err := d.RollbackPreview(&snaps[0])
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if strings.Join(plan.Snapshots, " ") != fs+"@c "+fs+"@b" ||
+		strings.Join(plan.Bookmarks, " ") != fs+"#b" ||
+		strings.Join(plan.Clones, " ") != TSTPoolName+"/rollback-clone" ||
+		strings.Join(plan.Held, " ") != fs+"@b" {
+		t.Errorf("Unexpected rollback plan %+v", plan)
+		return
+	}
+	if _, err = d.RollbackTo(&snaps[0], zfs.RollbackOptions{DestroyClones: true}); err == nil {
+		t.Error("Rollback with held snapshot to destroy succeeded")
+		return
+	}
+	if err = snaps[1].Release("keep"); err != nil {
+		t.Error(err)
+		return
+	}
+	if plan, err = d.RollbackPreview(&snaps[0]); err != nil {
+		t.Error(err)
+		return
+	}
+	s, err := zfs.DatasetSnapshot(fs+"@d", false, props)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	s.Close()
+	if _, err = d.RollbackTo(&snaps[0], zfs.RollbackOptions{DestroyClones: true,
+		Confirmed: &plan}); err == nil {
+		t.Error("Rollback destroyed snapshot created after preview")
+		return
+	}
+	if plan, err = d.RollbackPreview(&snaps[0]); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err = d.RollbackTo(&snaps[0], zfs.RollbackOptions{}); err == nil {
+		t.Error("Rollback destroyed newer snapshots without DestroyNewer")
+		return
+	}
+	if _, err = d.RollbackTo(&snaps[0], zfs.RollbackOptions{DestroyNewer: true}); err == nil {
+		t.Error("Rollback destroyed clones without DestroyClones")
+		return
+	}
+	if _, err = d.RollbackTo(&snaps[0], zfs.RollbackOptions{DestroyClones: true,
+		Confirmed: &plan}); err != nil {
+		t.Error(err)
+		return
+	}
+	for _, name := range []string{fs + "@b", fs + "@c", fs + "@d", TSTPoolName + "/rollback-clone"} {
+		if c, err := zfs.DatasetOpenSingle(name); err == nil {
+			c.Close()
+			t.Errorf("%s not destroyed on rollback", name)
+			return
+		}
+	}
+	print("PASS\n\n")
+}
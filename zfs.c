@@ -3,6 +3,7 @@
  */
 
 #include <libzfs.h>
+#include <libzfs_core.h>
 #include <memory.h>
 #include <string.h>
 #include <stdio.h>
@@ -73,7 +74,7 @@ int dataset_type(dataset_list_ptr dataset) {
 
 dataset_list_ptr dataset_open(const char *path) {
 	dataset_list_ptr list = create_dataset_list_item();
-	list->zh = zfs_open(libzfsHandle, path, 0xF);
+	list->zh = zfs_open(libzfsHandle, path, 0x1F);
 	if (list->zh == NULL) {
 		dataset_list_free(list);
 		list = NULL;
@@ -85,14 +86,90 @@ int dataset_create(const char *path, zfs_type_t type, nvlist_ptr props) {
 	return zfs_create(libzfsHandle, path, type, props);
 }
 
+/*
+ * Create encrypted dataset with wrapping key supplied by caller, instead of
+ * reading it from keylocation as zfs_create does. Returns -1 if properties
+ * are not valid (libzfs error is set), or errno of lzc_create.
+ */
+int dataset_create_crypt(const char *path, zfs_type_t type, nvlist_ptr props,
+	uint8_t *wkeydata, uint_t wkeylen) {
+	char errbuf[1024];
+	char poolname[ZFS_MAX_DATASET_NAME_LEN];
+	zpool_handle_t *zph;
+	nvlist_t *valid;
+	int ret;
+
+	snprintf(errbuf, sizeof (errbuf), "cannot create '%s'", path);
+	strlcpy(poolname, path, sizeof (poolname));
+	poolname[strcspn(poolname, "/@#")] = '\0';
+	if ((zph = zpool_open(libzfsHandle, poolname)) == NULL) {
+		return -1;
+	}
+	valid = zfs_valid_proplist(libzfsHandle, type, props, 0, NULL, zph,
+		B_TRUE, errbuf);
+	zpool_close(zph);
+	if (valid == NULL) {
+		return -1;
+	}
+	ret = lzc_create(path, type == ZFS_TYPE_VOLUME ?
+		LZC_DATSET_TYPE_ZVOL : LZC_DATSET_TYPE_ZFS, valid, wkeydata, wkeylen);
+	nvlist_free(valid);
+	return ret;
+}
+
+int dataset_load_key(dataset_list_ptr dataset, uint8_t *wkeydata, uint_t wkeylen) {
+	if (wkeydata == NULL) {
+		return zfs_crypto_load_key(dataset->zh, B_FALSE, NULL);
+	}
+	return lzc_load_key(zfs_get_name(dataset->zh), B_FALSE, wkeydata, wkeylen);
+}
+
+/*
+ * Change wrapping key of dataset to key supplied by caller, or inherit
+ * key of parent if wkeydata is NULL. Returns -1 if properties are not
+ * valid (libzfs error is set), or errno of lzc_change_key.
+ */
+int dataset_change_key(dataset_list_ptr dataset, nvlist_ptr props,
+	uint8_t *wkeydata, uint_t wkeylen) {
+	char errbuf[1024];
+	nvlist_t *valid;
+	int ret;
+
+	if (wkeydata == NULL) {
+		return lzc_change_key(zfs_get_name(dataset->zh), DCP_CMD_INHERIT,
+			NULL, NULL, 0);
+	}
+	snprintf(errbuf, sizeof (errbuf), "cannot change key for '%s'",
+		zfs_get_name(dataset->zh));
+	valid = zfs_valid_proplist(libzfsHandle, zfs_get_type(dataset->zh), props,
+		zfs_prop_get_int(dataset->zh, ZFS_PROP_ZONED), NULL,
+		zfs_get_pool_handle(dataset->zh), B_TRUE, errbuf);
+	if (valid == NULL) {
+		return -1;
+	}
+	ret = lzc_change_key(zfs_get_name(dataset->zh), DCP_CMD_NEW_KEY, valid,
+		wkeydata, wkeylen);
+	nvlist_free(valid);
+	return ret;
+}
+
 int dataset_destroy(dataset_list_ptr dataset, boolean_t defer) {
 	return zfs_destroy(dataset->zh, defer);
 }
 
 dataset_list_t *dataset_list_children(dataset_list_t *dataset) {
 	int err = 0;
-	dataset_list_t *zlist = create_dataset_list_item();
+	zfs_type_t type = zfs_get_type(dataset->zh);
+	dataset_list_t *zlist = NULL;
+	// bookmarks have no children
+	if (type == ZFS_TYPE_BOOKMARK) {
+		return NULL;
+	}
+	zlist = create_dataset_list_item();
 	err = zfs_iter_children(dataset->zh, dataset_list_callb, &zlist);
+	if (err == 0 && type != ZFS_TYPE_SNAPSHOT) {
+		err = zfs_iter_bookmarks(dataset->zh, dataset_list_callb, &zlist);
+	}
 	if ( err != 0  || zlist->zh == NULL) {
 		dataset_list_free(zlist);
 		return NULL;
@@ -100,6 +177,68 @@ dataset_list_t *dataset_list_children(dataset_list_t *dataset) {
 	return zlist;
 }
 
+/*
+ * List filesystem and volume children of dataset, and its snapshots and
+ * bookmarks only if included in types, so they are not opened needlessly.
+ */
+dataset_list_t *dataset_list_children_types(dataset_list_t *dataset, int types) {
+	int err = 0;
+	zfs_type_t type = zfs_get_type(dataset->zh);
+	dataset_list_t *zlist = NULL;
+	if (type == ZFS_TYPE_SNAPSHOT || type == ZFS_TYPE_BOOKMARK) {
+		return NULL;
+	}
+	zlist = create_dataset_list_item();
+	err = zfs_iter_filesystems(dataset->zh, dataset_list_callb, &zlist);
+	if (err == 0 && (types & ZFS_TYPE_SNAPSHOT)) {
+		err = zfs_iter_snapshots(dataset->zh, B_FALSE, dataset_list_callb,
+			&zlist, 0, 0);
+	}
+	if (err == 0 && (types & ZFS_TYPE_BOOKMARK)) {
+		err = zfs_iter_bookmarks(dataset->zh, dataset_list_callb, &zlist);
+	}
+	if ( err != 0  || zlist->zh == NULL) {
+		dataset_list_free(zlist);
+		return NULL;
+	}
+	return zlist;
+}
+
+static int dataset_name_callb(zfs_handle_t *dataset, void *data) {
+	int err = nvlist_add_boolean((nvlist_t *)data, zfs_get_name(dataset));
+	zfs_close(dataset);
+	return err;
+}
+
+/*
+ * Names of children of dataset the way dataset_list_children_types lists
+ * them, in order of libzfs iteration, without keeping them open.
+ */
+nvlist_ptr dataset_children_names(dataset_list_t *dataset, int types) {
+	int err = 0;
+	zfs_type_t type = zfs_get_type(dataset->zh);
+	nvlist_t *names = NULL;
+	if (type == ZFS_TYPE_SNAPSHOT || type == ZFS_TYPE_BOOKMARK) {
+		return NULL;
+	}
+	if (nvlist_alloc(&names, NV_UNIQUE_NAME, 0) != 0) {
+		return NULL;
+	}
+	err = zfs_iter_filesystems(dataset->zh, dataset_name_callb, names);
+	if (err == 0 && (types & ZFS_TYPE_SNAPSHOT)) {
+		err = zfs_iter_snapshots(dataset->zh, B_TRUE, dataset_name_callb,
+			names, 0, 0);
+	}
+	if (err == 0 && (types & ZFS_TYPE_BOOKMARK)) {
+		err = zfs_iter_bookmarks(dataset->zh, dataset_name_callb, names);
+	}
+	if (err != 0) {
+		nvlist_free(names);
+		return NULL;
+	}
+	return names;
+}
+
 zpool_list_ptr dataset_get_pool(dataset_list_ptr dataset) {
 	zpool_list_ptr pool = create_zpool_list_item();
 	if(pool != NULL) {
@@ -116,6 +255,78 @@ int dataset_user_prop_set(dataset_list_ptr dataset, const char *prop, const char
 	return zfs_prop_set(dataset->zh, prop, value);
 }
 
+int dataset_prop_set_list(dataset_list_ptr dataset, nvlist_ptr props) {
+	return zfs_prop_set_list(dataset->zh, props);
+}
+
+int dataset_prop_validate(dataset_list_ptr dataset, nvlist_ptr props) {
+	char errbuf[1024];
+	nvlist_t *valid;
+
+	snprintf(errbuf, sizeof (errbuf), "cannot set property for '%s'",
+		zfs_get_name(dataset->zh));
+	valid = zfs_valid_proplist(libzfsHandle, zfs_get_type(dataset->zh), props,
+		zfs_prop_get_int(dataset->zh, ZFS_PROP_ZONED), dataset->zh,
+		zfs_get_pool_handle(dataset->zh), B_FALSE, errbuf);
+	if (valid == NULL) {
+		return -1;
+	}
+	nvlist_free(valid);
+	return 0;
+}
+
+/*
+ * Validate dataset property value as on dataset creation and store value
+ * normalized by zfs_valid_proplist (sizes in bytes, index names) in
+ * normalized. Returns -1 with libzfs error set if value is not valid.
+ */
+int validate_dataset_prop(zfs_type_t type, zfs_prop_t prop, const char *value,
+	char *normalized, size_t len) {
+	char errbuf[1024];
+	const char *name = zfs_prop_to_name(prop);
+	const char *strindex;
+	nvlist_t *props, *valid;
+	uint64_t num;
+	char *str;
+
+	if (nvlist_alloc(&props, NV_UNIQUE_NAME, 0) != 0) {
+		return -1;
+	}
+	if (nvlist_add_string(props, name, value) != 0) {
+		nvlist_free(props);
+		return -1;
+	}
+	snprintf(errbuf, sizeof (errbuf), "cannot set property '%s'", name);
+	valid = zfs_valid_proplist(libzfsHandle, type, props, 0, NULL, NULL,
+		B_TRUE, errbuf);
+	nvlist_free(props);
+	if (valid == NULL) {
+		return -1;
+	}
+	if (nvlist_lookup_string(valid, name, &str) == 0) {
+		strlcpy(normalized, str, len);
+	} else if (nvlist_lookup_uint64(valid, name, &num) == 0) {
+		if (zfs_prop_get_type(prop) == PROP_TYPE_INDEX &&
+			zfs_prop_index_to_string(prop, num, &strindex) == 0) {
+			strlcpy(normalized, strindex, len);
+		} else {
+			snprintf(normalized, len, "%llu", (unsigned long long)num);
+		}
+	} else {
+		strlcpy(normalized, value, len);
+	}
+	nvlist_free(valid);
+	return 0;
+}
+
+int dataset_prop_inherit(dataset_list_ptr dataset, const char *prop, boolean_t received) {
+	return zfs_prop_inherit(dataset->zh, prop, received);
+}
+
+int dataset_prop_valid(dataset_list_ptr dataset, zfs_prop_t prop) {
+	return zfs_prop_valid_for_type(prop, zfs_get_type(dataset->zh), B_FALSE);
+}
+
 int dataset_clone(dataset_list_ptr dataset, const char *target, nvlist_ptr props) {
 	return zfs_clone(dataset->zh, target, props);
 }
@@ -124,10 +335,71 @@ int dataset_snapshot(const char *path, boolean_t recur, nvlist_ptr props) {
 	return zfs_snapshot(libzfsHandle, path, recur, props);
 }
 
+/*
+ * Snapshot all snaps in single transaction group, like zfs_snapshot_nvl, but
+ * return errors of individual snapshots in errlist instead of printing them.
+ * Returns -1 with libzfs error set if props are not valid.
+ */
+int dataset_snapshot_many(nvlist_ptr snaps, nvlist_ptr props, nvlist_ptr *errlist) {
+	char errbuf[1024];
+	nvlist_t *valid = NULL;
+	int ret;
+
+	*errlist = NULL;
+	if (props != NULL) {
+		snprintf(errbuf, sizeof (errbuf), "cannot create snapshots");
+		valid = zfs_valid_proplist(libzfsHandle, ZFS_TYPE_SNAPSHOT, props,
+			B_FALSE, NULL, NULL, B_FALSE, errbuf);
+		if (valid == NULL) {
+			return -1;
+		}
+	}
+	ret = lzc_snapshot(snaps, valid, errlist);
+	nvlist_free(valid);
+	return ret;
+}
+
+int dataset_destroy_snaps(nvlist_ptr snaps, boolean_t defer, nvlist_ptr *errlist) {
+	*errlist = NULL;
+	return lzc_destroy_snaps(snaps, defer, errlist);
+}
+
+int dataset_snaprange_space(const char *first, const char *last, uint64_t *used) {
+	return lzc_snaprange_space(first, last, used);
+}
+
+int dataset_hold_many(nvlist_ptr holds, int cleanup_fd, nvlist_ptr *errlist) {
+	*errlist = NULL;
+	return lzc_hold(holds, cleanup_fd, errlist);
+}
+
+int dataset_release_many(nvlist_ptr holds, nvlist_ptr *errlist) {
+	*errlist = NULL;
+	return lzc_release(holds, errlist);
+}
+
 int dataset_rollback(dataset_list_ptr dataset, dataset_list_ptr snapshot, boolean_t force) {
 	return zfs_rollback(dataset->zh, snapshot->zh, force);
 }
 
+int dataset_bookmark(const char *source, const char *bookmark) {
+	nvlist_t *bmarks = NULL;
+	int err = nvlist_alloc(&bmarks, NV_UNIQUE_NAME, 0);
+	if (err != 0) {
+		return err;
+	}
+	err = nvlist_add_string(bmarks, bookmark, source);
+	if (err == 0) {
+		err = lzc_bookmark(bmarks, NULL);
+	}
+	nvlist_free(bmarks);
+	return err;
+}
+
+int dataset_send_one(dataset_list_ptr snapshot, const char *from, int fd, sendflags_t *flags) {
+	return zfs_send_one(snapshot->zh, from, fd, flags, NULL);
+}
+
 int dataset_promote(dataset_list_ptr dataset) {
 	return zfs_promote(dataset->zh);
 }
@@ -178,6 +450,9 @@ property_list_t *read_dataset_property(dataset_list_t *dataset, int prop) {
 	if (r == 0 && list != NULL) {
 		// strcpy(list->name, zpool_prop_to_name(prop));
 		zprop_source_tostr(list->source, source);
+		if (source == ZPROP_SRC_INHERITED) {
+			strlcpy(list->origin, statbuf, sizeof (list->origin));
+		}
 		list->property = (int)prop;
 	} else if (list != NULL) {
 		free_properties(list);
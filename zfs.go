@@ -10,10 +10,12 @@ import "C"
 import (
 	"errors"
 	"fmt"
+	"os"
 	"path"
 	"sort"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 	"unsafe"
 )
@@ -56,14 +58,34 @@ type Dataset struct {
 	Children   []Dataset
 }
 
-func (d *Dataset) openChildren() (err error) {
+// DatasetOpenOptions options of DatasetOpenWithOptions and
+// DatasetOpenAllWithOptions
+type DatasetOpenOptions struct {
+	// Props properties loaded when datasets are opened, all properties if
+	// nil. Name is always loaded. Other properties are loaded on demand with
+	// GetProperty or LoadProperties, which makes listing of many datasets
+	// much faster.
+	Props []Prop
+}
+
+// loadOpenProperties loads properties of just opened dataset
+func (d *Dataset) loadOpenProperties(opts *DatasetOpenOptions) (err error) {
+	if opts == nil || opts.Props == nil {
+		return d.ReloadProperties()
+	}
+	d.Properties = make(map[Prop]Property)
+	// stats are fetched on open, no need to refresh them
+	return d.loadProperties(append([]Prop{DatasetPropName}, opts.Props...), false)
+}
+
+func (d *Dataset) openChildren(opts *DatasetOpenOptions) (err error) {
 	d.Children = make([]Dataset, 0, 5)
 	list := C.dataset_list_children(d.list)
 	for list != nil {
 		dataset := Dataset{list: list, closeOnce: new(sync.Once)}
 		dataset.Type = DatasetType(C.dataset_type(list))
 		dataset.Properties = make(map[Prop]Property)
-		err = dataset.ReloadProperties()
+		err = dataset.loadOpenProperties(opts)
 		if err != nil {
 			return
 		}
@@ -71,7 +93,7 @@ func (d *Dataset) openChildren() (err error) {
 		list = C.dataset_next(list)
 	}
 	for ci := range d.Children {
-		if err = d.Children[ci].openChildren(); err != nil {
+		if err = d.Children[ci].openChildren(opts); err != nil {
 			return
 		}
 	}
@@ -81,6 +103,12 @@ func (d *Dataset) openChildren() (err error) {
 // DatasetOpenAll recursive get handles to all available datasets on system
 // (file-systems, volumes or snapshots).
 func DatasetOpenAll() (datasets []Dataset, err error) {
+	return DatasetOpenAllWithOptions(nil)
+}
+
+// DatasetOpenAllWithOptions is DatasetOpenAll loading properties as set by
+// opts, nil opts loads all properties
+func DatasetOpenAllWithOptions(opts *DatasetOpenOptions) (datasets []Dataset, err error) {
 	list := C.dataset_list_root()
 	for list != nil {
 		dataset := Dataset{
@@ -89,7 +117,7 @@ func DatasetOpenAll() (datasets []Dataset, err error) {
 			Type:      DatasetType(C.dataset_type(list)),
 		}
 		dataset.Type = DatasetType(C.dataset_type(list))
-		err = dataset.ReloadProperties()
+		err = dataset.loadOpenProperties(opts)
 		if err != nil {
 			return
 		}
@@ -97,7 +125,7 @@ func DatasetOpenAll() (datasets []Dataset, err error) {
 		list = C.dataset_next(list)
 	}
 	for ci := range datasets {
-		if err = datasets[ci].openChildren(); err != nil {
+		if err = datasets[ci].openChildren(opts); err != nil {
 			return
 		}
 	}
@@ -114,16 +142,26 @@ func DatasetCloseAll(datasets []Dataset) {
 
 // DatasetOpen open dataset and all of its recursive children datasets
 func DatasetOpen(path string) (d Dataset, err error) {
-	if d, err = DatasetOpenSingle(path); err != nil {
+	return DatasetOpenWithOptions(path, nil)
+}
+
+// DatasetOpenWithOptions is DatasetOpen loading properties as set by opts,
+// nil opts loads all properties
+func DatasetOpenWithOptions(path string, opts *DatasetOpenOptions) (d Dataset, err error) {
+	if d, err = datasetOpenSingle(path, opts); err != nil {
 		return
 	}
-	err = d.openChildren()
+	err = d.openChildren(opts)
 	return
 }
 
 // DatasetOpenSingle open dataset without opening all of its recursive
 // children datasets
 func DatasetOpenSingle(path string) (d Dataset, err error) {
+	return datasetOpenSingle(path, nil)
+}
+
+func datasetOpenSingle(path string, opts *DatasetOpenOptions) (d Dataset, err error) {
 	csPath := C.CString(path)
 	d.list = C.dataset_open(csPath)
 	C.free(unsafe.Pointer(csPath))
@@ -138,7 +176,7 @@ func DatasetOpenSingle(path string) (d Dataset, err error) {
 	}
 	d.closeOnce = new(sync.Once)
 	d.Type = DatasetType(C.dataset_type(d.list))
-	err = d.ReloadProperties()
+	err = d.loadOpenProperties(opts)
 	if err != nil {
 		return
 	}
@@ -167,9 +205,20 @@ func datasetPropertiesTonvlist(props map[Prop]Property) (
 }
 
 // DatasetCreate create a new filesystem or volume on path representing
-// pool/dataset or pool/parent/dataset
+// pool/dataset or pool/parent/dataset. With optional key, only one is
+// accepted, it is created as encryption root with key material from
+// memory. Encryption is then on if not set in props, and keylocation is
+// prompt if not set in props or key.
 func DatasetCreate(path string, dtype DatasetType,
-	props map[Prop]Property) (d Dataset, err error) {
+	props map[Prop]Property, key ...DatasetKey) (d Dataset, err error) {
+	switch len(key) {
+	case 0:
+	case 1:
+		return datasetCreateEncrypted(path, dtype, props, key[0])
+	default:
+		err = errors.New("Only one dataset key can be given")
+		return
+	}
 	var cprops C.nvlist_ptr
 	if cprops, err = datasetPropertiesTonvlist(props); err != nil {
 		return
@@ -212,8 +261,10 @@ func (d *Dataset) reOpen() (err error) {
 // isn't mounted, and that there are no active dependents. Set Defer argument
 // to true to defer destruction for when dataset is not in use. Call Close() to
 // cleanup memory.
+// Bookmarks are destroyed the same way, bookmarks of dataset are destroyed
+// with it.
 func (d *Dataset) Destroy(Defer bool) (err error) {
-	if len(d.Children) > 0 {
+	if d.hasChildren() {
 		path, e := d.Path()
 		if e != nil {
 			return
@@ -236,6 +287,23 @@ func (d *Dataset) Destroy(Defer bool) (err error) {
 	return
 }
 
+// hasChildren - return true if dataset has children other than bookmarks
+func (d *Dataset) hasChildren() bool {
+	for _, ch := range d.Children {
+		if !ch.IsBookmark() {
+			return true
+		}
+	}
+	return false
+}
+
+// IsBookmark - return true if dataset is bookmark
+func (d *Dataset) IsBookmark() (ok bool) {
+	path := d.Properties[DatasetPropName].Value
+	ok = (d.Type == DatasetTypeBookmark || strings.Contains(path, "#"))
+	return
+}
+
 // IsSnapshot - retrun true if datset is snapshot
 func (d *Dataset) IsSnapshot() (ok bool) {
 	path := d.Properties[DatasetPropName].Value
@@ -275,8 +343,8 @@ func (d *Dataset) DestroyRecursive() (err error) {
 				if path, err = c.Path(); err != nil {
 					return
 				}
-				if strings.Contains(path, "@") {
-					continue // skip other snapshots
+				if strings.ContainsAny(path, "@#") {
+					continue // skip other snapshots and bookmarks
 				}
 				if c, err = DatasetOpen(path + "@" + snapname); err != nil {
 					continue
@@ -320,26 +388,57 @@ func (d *Dataset) PoolName() string {
 
 // ReloadProperties re-read dataset's properties
 func (d *Dataset) ReloadProperties() (err error) {
-	Global.Mtx.Lock()
-	defer Global.Mtx.Unlock()
 	if d.list == nil {
 		err = errors.New(msgDatasetIsNil)
 		return
 	}
-	d.Properties = make(map[Prop]Property)
-	C.zfs_refresh_properties(d.list.zh)
+	props := make([]Prop, 0, DatasetNumProps)
 	for prop := DatasetPropType; prop < DatasetNumProps; prop++ {
+		props = append(props, prop)
+	}
+	d.Properties = make(map[Prop]Property)
+	return d.loadProperties(props, true)
+}
+
+// LoadProperties re-read only given dataset's properties, other properties
+// in Properties are left as they are
+func (d *Dataset) LoadProperties(props ...Prop) (err error) {
+	if d.list == nil {
+		err = errors.New(msgDatasetIsNil)
+		return
+	}
+	return d.loadProperties(props, true)
+}
+
+func (d *Dataset) loadProperties(props []Prop, refresh bool) (err error) {
+	Global.Mtx.Lock()
+	defer Global.Mtx.Unlock()
+	if refresh {
+		C.zfs_refresh_properties(d.list.zh)
+	}
+	for _, prop := range props {
 		plist := C.read_dataset_property(d.list, C.int(prop))
 		if plist == nil {
 			continue
 		}
 		d.Properties[prop] = Property{Value: C.GoString(&(*plist).value[0]),
-			Source: C.GoString(&(*plist).source[0])}
+			Source:        C.GoString(&(*plist).source[0]),
+			InheritedFrom: C.GoString(&(*plist).origin[0])}
 		C.free_properties(plist)
 	}
 	return
 }
 
+// propertyValue returns value of property, loaded with GetProperty if it
+// was not loaded on open, empty if it can't be loaded
+func (d *Dataset) propertyValue(p Prop) string {
+	prop, ok := d.Properties[p]
+	if !ok {
+		prop, _ = d.GetProperty(p)
+	}
+	return prop.Value
+}
+
 // GetProperty reload and return single specified property. This also reloads requested
 // property in Properties map.
 func (d *Dataset) GetProperty(p Prop) (prop Property, err error) {
@@ -356,7 +455,8 @@ func (d *Dataset) GetProperty(p Prop) (prop Property, err error) {
 	}
 	defer C.free_properties(plist)
 	prop = Property{Value: C.GoString(&(*plist).value[0]),
-		Source: C.GoString(&(*plist).source[0])}
+		Source:        C.GoString(&(*plist).source[0]),
+		InheritedFrom: C.GoString(&(*plist).origin[0])}
 	d.Properties[p] = prop
 	return
 }
@@ -407,7 +507,8 @@ func (d *Dataset) SetProperty(p Prop, value string) (err error) {
 	}
 	defer C.free_properties(plist)
 	d.Properties[p] = Property{Value: C.GoString(&(*plist).value[0]),
-		Source: C.GoString(&(*plist).source[0])}
+		Source:        C.GoString(&(*plist).source[0]),
+		InheritedFrom: C.GoString(&(*plist).origin[0])}
 	return
 }
 
@@ -430,6 +531,199 @@ func (d *Dataset) SetUserProperty(prop, value string) (err error) {
 	return
 }
 
+// PropertyErrors errors of invalid properties by property name, returned
+// by SetProperties
+type PropertyErrors map[string]error
+
+func (e PropertyErrors) Error() string {
+	return formatNamedErrors("Invalid properties", e)
+}
+
+// formatNamedErrors formats errors by name sorted by name
+func formatNamedErrors(prefix string, errs map[string]error) string {
+	names := make([]string, 0, len(errs))
+	for name := range errs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	msgs := make([]string, len(names))
+	for i, name := range names {
+		msgs[i] = fmt.Sprintf("%s: %s", name, errs[name].Error())
+	}
+	return prefix + ": " + strings.Join(msgs, "; ")
+}
+
+// SetProperties sets native and user properties of the dataset at once,
+// in single ioctl. Every value is validated first, if validation fails
+// PropertyErrors with errors of all invalid properties is returned and
+// nothing is set. Valid values can still be refused by the kernel (e.g.
+// quota below used space), it sets properties one by one, so the others
+// than the refused ones may stay set. Properties member is reloaded anyway.
+func (d *Dataset) SetProperties(props map[Prop]string, user map[string]string) (err error) {
+	if d.list == nil {
+		err = errors.New(msgDatasetIsNil)
+		return
+	}
+	all := make(map[string]string, len(props)+len(user))
+	for p, value := range props {
+		all[DatasetPropertyToName(p)] = value
+	}
+	for prop, value := range user {
+		all[prop] = value
+	}
+	if len(all) == 0 {
+		return
+	}
+	perrs := make(PropertyErrors)
+	for name, value := range all {
+		if e := d.validateProperty(name, value); e != nil {
+			perrs[name] = e
+		}
+	}
+	if len(perrs) > 0 {
+		err = perrs
+		return
+	}
+	err = d.setPropertyList(all)
+	// Update Properties member with changes made
+	if rerr := d.ReloadProperties(); err == nil {
+		err = rerr
+	}
+	return
+}
+
+func (d *Dataset) validateProperty(name, value string) (err error) {
+	var cprops C.nvlist_ptr
+	if cprops, err = stringsToNvlist(map[string]string{name: value}); err != nil {
+		return
+	}
+	defer C.nvlist_free(cprops)
+	Global.Mtx.Lock()
+	defer Global.Mtx.Unlock()
+	if C.dataset_prop_validate(d.list, cprops) != 0 {
+		err = LastError()
+	}
+	return
+}
+
+func (d *Dataset) setPropertyList(props map[string]string) (err error) {
+	var cprops C.nvlist_ptr
+	if cprops, err = stringsToNvlist(props); err != nil {
+		return
+	}
+	defer C.nvlist_free(cprops)
+	Global.Mtx.Lock()
+	defer Global.Mtx.Unlock()
+	if C.dataset_prop_set_list(d.list, cprops) != 0 {
+		err = LastError()
+	}
+	return
+}
+
+func stringsToNvlist(props map[string]string) (cprops C.nvlist_ptr, err error) {
+	if cprops = C.new_property_nvlist(); cprops == nil {
+		err = errors.New("Failed to allocate properties")
+		return
+	}
+	for name, value := range props {
+		csName := C.CString(name)
+		csValue := C.CString(value)
+		r := C.property_nvlist_add(cprops, csName, csValue)
+		C.free(unsafe.Pointer(csName))
+		C.free(unsafe.Pointer(csValue))
+		if r != 0 {
+			C.nvlist_free(cprops)
+			cprops = nil
+			err = errors.New("Failed to convert property")
+			return
+		}
+	}
+	return
+}
+
+// InheritProperty clears local value of dataset property so it is inherited
+// from parent dataset, or default value is used, like zfs inherit. Set
+// recursive to inherit the property on all descendent datasets, and
+// received to revert to received value if there is one (zfs inherit -S).
+func (d *Dataset) InheritProperty(p Prop, recursive bool, received bool) (err error) {
+	if d.list == nil {
+		err = errors.New(msgDatasetIsNil)
+		return
+	}
+	name := DatasetPropertyToName(p)
+	err = d.inheritRecursive(name, recursive, received, func(ds *Dataset) bool {
+		return C.dataset_prop_valid(ds.list, C.zfs_prop_t(p)) != 0
+	})
+	return
+}
+
+// InheritUserProperty is InheritProperty for user properties
+func (d *Dataset) InheritUserProperty(prop string, recursive bool, received bool) (err error) {
+	if d.list == nil {
+		err = errors.New(msgDatasetIsNil)
+		return
+	}
+	err = d.inheritRecursive(prop, recursive, received, func(ds *Dataset) bool {
+		return true
+	})
+	return
+}
+
+// inheritRecursive inherits property on dataset, and descendent datasets,
+// including snapshots, the property is valid for if recursive, then reloads
+// properties of the dataset and its open children
+func (d *Dataset) inheritRecursive(prop string, recursive, received bool,
+	valid func(ds *Dataset) bool) (err error) {
+	if err = d.inherit(prop, received); err != nil {
+		return
+	}
+	if recursive {
+		var path string
+		if path, err = d.Path(); err != nil {
+			return
+		}
+		opts := &DatasetIterOptions{
+			Types: DatasetTypeFilesystem | DatasetTypeVolume | DatasetTypeSnapshot,
+			Props: []Prop{},
+		}
+		err = IterDatasets(path, opts, func(ds *Dataset) error {
+			if ds.Properties[DatasetPropName].Value == path || !valid(ds) {
+				return nil
+			}
+			return ds.inherit(prop, received)
+		})
+		if err != nil {
+			return
+		}
+	}
+	err = d.reloadAll()
+	return
+}
+
+func (d *Dataset) inherit(prop string, received bool) (err error) {
+	Global.Mtx.Lock()
+	defer Global.Mtx.Unlock()
+	csProp := C.CString(prop)
+	defer C.free(unsafe.Pointer(csProp))
+	if errcode := C.dataset_prop_inherit(d.list, csProp, booleanT(received)); errcode != 0 {
+		err = LastError()
+	}
+	return
+}
+
+// reloadAll reloads properties of dataset and all its open children
+func (d *Dataset) reloadAll() (err error) {
+	if err = d.ReloadProperties(); err != nil {
+		return
+	}
+	for i := range d.Children {
+		if err = d.Children[i].reloadAll(); err != nil {
+			return
+		}
+	}
+	return
+}
+
 // Clone - clones the dataset.  The target must be of the same type as
 // the source.
 func (d *Dataset) Clone(target string, props map[Prop]Property) (rd Dataset, err error) {
@@ -469,6 +763,135 @@ func DatasetSnapshot(path string, recur bool, props map[Prop]Property) (rd Datas
 	return
 }
 
+// SnapshotErrors errors of individual snapshots by snapshot name, returned
+// by SnapshotMany
+type SnapshotErrors map[string]error
+
+func (e SnapshotErrors) Error() string {
+	return formatNamedErrors("Failed to create snapshots", e)
+}
+
+// SnapshotMany atomically creates snapshots with given full names
+// (pool/dataset@snapshot) of any datasets of the same pool, all in the
+// same transaction group. Props are user properties set on all snapshots.
+// Unlike holds, snapshots of missing datasets are not skipped: if any
+// snapshot, including one of a missing dataset (ENOENT), can't be created
+// none are, and SnapshotErrors with error of each of them is returned.
+func SnapshotMany(names []string, props map[string]string) (err error) {
+	var snaps, cprops, errlist C.nvlist_ptr
+	if len(names) == 0 {
+		return
+	}
+	for _, name := range names {
+		csName := C.CString(name)
+		valid := C.zfs_name_valid(csName, C.ZFS_TYPE_SNAPSHOT)
+		C.free(unsafe.Pointer(csName))
+		if valid == 0 {
+			err = fmt.Errorf("Invalid snapshot name '%s'", name)
+			return
+		}
+	}
+	if snaps, err = namesToNvlist(names); err != nil {
+		return
+	}
+	defer C.nvlist_free(snaps)
+	if len(props) > 0 {
+		if cprops, err = stringsToNvlist(props); err != nil {
+			return
+		}
+		defer C.nvlist_free(cprops)
+	}
+	r := C.dataset_snapshot_many(snaps, cprops, &errlist)
+	if errlist != nil {
+		defer C.nvlist_free(errlist)
+	}
+	switch {
+	case r == 0:
+		return
+	case r == -1:
+		err = LastError()
+		return
+	}
+	serrs := SnapshotErrors(errlistToMap(errlist))
+	if len(serrs) == 0 {
+		err = fmt.Errorf("Failed to create snapshots: %s", syscall.Errno(r))
+		return
+	}
+	err = serrs
+	return
+}
+
+// namesToNvlist converts names to nvlist of boolean (name only) pairs, as
+// libzfs_core takes lists of snapshots and hold tags
+func namesToNvlist(names []string) (list C.nvlist_ptr, err error) {
+	if r := C.nvlist_alloc(&list, C.NV_UNIQUE_NAME, 0); r != 0 {
+		err = errors.New("Failed to allocate snapshot list")
+		return
+	}
+	for _, name := range names {
+		csName := C.CString(name)
+		r := C.nvlist_add_boolean(list, csName)
+		C.free(unsafe.Pointer(csName))
+		if r != 0 {
+			C.nvlist_free(list)
+			list = nil
+			err = fmt.Errorf("Failed to add '%s' to list: %s", name,
+				syscall.Errno(r))
+			return
+		}
+	}
+	return
+}
+
+// errlistToMap converts errlist returned by libzfs_core, errno by dataset
+// name, to map of errors
+func errlistToMap(errlist C.nvlist_ptr) (errs map[string]error) {
+	errs = make(map[string]error)
+	for nvp := C.nvlist_next_nvpair(errlist, nil); nvp != nil; nvp = C.nvlist_next_nvpair(errlist, nvp) {
+		var errno C.int32_t
+		C.nvpair_value_int32(nvp, &errno)
+		name := C.GoString(C.nvpair_name(nvp))
+		if name == "N_MORE_ERRORS" {
+			errs[name] = fmt.Errorf("%d more errors", errno)
+			continue
+		}
+		errs[name] = syscall.Errno(errno)
+	}
+	return
+}
+
+// Bookmark creates bookmark of snapshot, or copy of bookmark. Name is full
+// bookmark name (pool/dataset#name), or name relative to the dataset
+// ('#name' or 'name'). Bookmark can be used as incremental send source
+// after the snapshot is destroyed, see SendOne.
+func (d *Dataset) Bookmark(name string) (bookmark Dataset, err error) {
+	var source string
+	if source, err = d.Path(); err != nil {
+		return
+	}
+	if d.Type != DatasetTypeSnapshot && d.Type != DatasetTypeBookmark {
+		err = fmt.Errorf("'%s' is not a snapshot or bookmark", source)
+		return
+	}
+	if !strings.Contains(name, "#") {
+		name = "#" + name
+	}
+	if name[0] == '#' {
+		name = source[:strings.IndexAny(source, "@#")] + name
+	}
+	csSource := C.CString(source)
+	defer C.free(unsafe.Pointer(csSource))
+	csName := C.CString(name)
+	defer C.free(unsafe.Pointer(csName))
+	if errc := C.dataset_bookmark(csSource, csName); errc != 0 {
+		err = fmt.Errorf("Failed to create bookmark '%s': %s", name,
+			syscall.Errno(errc))
+		return
+	}
+	bookmark, err = DatasetOpenSingle(name)
+	return
+}
+
 // Path return zfs dataset path/name
 func (d *Dataset) Path() (path string, err error) {
 	if d.list == nil {
@@ -480,7 +903,9 @@ func (d *Dataset) Path() (path string, err error) {
 	return
 }
 
-// Rollback rollabck's dataset snapshot
+// Rollback rollabck's dataset snapshot. Snapshots and bookmarks more recent
+// than snap are destroyed with their clones, see RollbackTo for checked
+// rollback.
 func (d *Dataset) Rollback(snap *Dataset, force bool) (err error) {
 	if d.list == nil {
 		err = errors.New(msgDatasetIsNil)
@@ -593,6 +1018,22 @@ func (d *Dataset) UnmountAll(flags int) (err error) {
 // Hold - Adds a single reference, named with the tag argument, to the snapshot.
 // Each snapshot has its own tag namespace, and tags must be unique within that space.
 func (d *Dataset) Hold(flag string) (err error) {
+	return d.hold(flag, false, -1)
+}
+
+// HoldRecursive adds hold, named with the tag argument, to the snapshot and
+// snapshots of the same name of all descendant datasets, at once. If
+// cleanup is not nil, holds are released when cleanup is closed, see
+// HoldCleanupFile.
+func (d *Dataset) HoldRecursive(tag string, cleanup *os.File) (err error) {
+	fd := -1
+	if cleanup != nil {
+		fd = int(cleanup.Fd())
+	}
+	return d.hold(tag, true, fd)
+}
+
+func (d *Dataset) hold(tag string, recursive bool, cleanupFd int) (err error) {
 	var path string
 	var pd Dataset
 	if path, err = d.Path(); err != nil {
@@ -609,9 +1050,9 @@ func (d *Dataset) Hold(flag string) (err error) {
 	defer pd.Close()
 	csSnapName := C.CString(path[strings.Index(path, "@")+1:])
 	defer C.free(unsafe.Pointer(csSnapName))
-	csFlag := C.CString(flag)
-	defer C.free(unsafe.Pointer(csFlag))
-	if 0 != C.zfs_hold(pd.list.zh, csSnapName, csFlag, booleanT(false), -1) {
+	csTag := C.CString(tag)
+	defer C.free(unsafe.Pointer(csTag))
+	if 0 != C.zfs_hold(pd.list.zh, csSnapName, csTag, booleanT(recursive), C.int(cleanupFd)) {
 		err = LastError()
 	}
 	return
@@ -621,6 +1062,16 @@ func (d *Dataset) Hold(flag string) (err error) {
 // The tag must already exist for each snapshot.  If a hold exists on a snapshot, attempts to destroy
 //  that snapshot by using the zfs destroy command return EBUSY.
 func (d *Dataset) Release(flag string) (err error) {
+	return d.release(flag, false)
+}
+
+// ReleaseRecursive removes hold, named with the tag argument, from the
+// snapshot and snapshots of the same name of all descendant datasets
+func (d *Dataset) ReleaseRecursive(tag string) (err error) {
+	return d.release(tag, true)
+}
+
+func (d *Dataset) release(tag string, recursive bool) (err error) {
 	var path string
 	var pd Dataset
 	if path, err = d.Path(); err != nil {
@@ -637,9 +1088,9 @@ func (d *Dataset) Release(flag string) (err error) {
 	defer pd.Close()
 	csSnapName := C.CString(path[strings.Index(path, "@")+1:])
 	defer C.free(unsafe.Pointer(csSnapName))
-	csFlag := C.CString(flag)
-	defer C.free(unsafe.Pointer(csFlag))
-	if 0 != C.zfs_release(pd.list.zh, csSnapName, csFlag, booleanT(false)) {
+	csTag := C.CString(tag)
+	defer C.free(unsafe.Pointer(csTag))
+	if 0 != C.zfs_release(pd.list.zh, csSnapName, csTag, booleanT(recursive)) {
 		err = LastError()
 	}
 	return
@@ -777,6 +1228,17 @@ func (d *Dataset) Snapshots() (snaps []Dataset, err error) {
 	return
 }
 
+// Bookmarks - filter and return all bookmarks of dataset
+func (d *Dataset) Bookmarks() (bookmarks []Dataset, err error) {
+	for _, ch := range d.Children {
+		if !ch.IsBookmark() {
+			continue
+		}
+		bookmarks = append(bookmarks, ch)
+	}
+	return
+}
+
 // FindSnapshot - returns true if given path is one of dataset snaphsots
 func (d *Dataset) FindSnapshot(path string) (ok bool, snap Dataset) {
 	for _, ch := range d.Children {
@@ -821,7 +1283,8 @@ func (d *Dataset) Clones() (clones []string, err error) {
 				if len(origin) > 0 {
 					if dIsSnapshot && origin == d.Properties[DatasetPropName].Value {
 						// if this dataset is snaphot
-						ch.Properties[DatasetNumProps+1000] = d.Properties[DatasetPropCreateTXG]
+						ch.Properties[DatasetNumProps+1000] = Property{
+							Value: d.propertyValue(DatasetPropCreateTXG)}
 						sortDesc = append(sortDesc, ch)
 					} else {
 						// Check if origin of this dataset is one of snapshots
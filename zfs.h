@@ -109,18 +109,35 @@ void dataset_list_free(dataset_list_t *list);
 
 dataset_list_t* dataset_list_root();
 dataset_list_t* dataset_list_children(dataset_list_t *dataset);
+dataset_list_t* dataset_list_children_types(dataset_list_t *dataset, int types);
+nvlist_ptr dataset_children_names(dataset_list_t *dataset, int types);
 dataset_list_t *dataset_next(dataset_list_t *dataset);
 int dataset_type(dataset_list_ptr dataset);
 
 dataset_list_ptr dataset_open(const char *path);
 int dataset_create(const char *path, zfs_type_t type, nvlist_ptr props);
+int dataset_create_crypt(const char *path, zfs_type_t type, nvlist_ptr props, uint8_t *wkeydata, uint_t wkeylen);
 int dataset_destroy(dataset_list_ptr dataset, boolean_t defer);
+int dataset_load_key(dataset_list_ptr dataset, uint8_t *wkeydata, uint_t wkeylen);
+int dataset_change_key(dataset_list_ptr dataset, nvlist_ptr props, uint8_t *wkeydata, uint_t wkeylen);
 zpool_list_ptr dataset_get_pool(dataset_list_ptr dataset);
 int dataset_prop_set(dataset_list_ptr dataset, zfs_prop_t prop, const char *value);
 int dataset_user_prop_set(dataset_list_ptr dataset, const char *prop, const char *value);
+int dataset_prop_set_list(dataset_list_ptr dataset, nvlist_ptr props);
+int dataset_prop_validate(dataset_list_ptr dataset, nvlist_ptr props);
+int validate_dataset_prop(zfs_type_t type, zfs_prop_t prop, const char *value, char *normalized, size_t len);
+int dataset_prop_inherit(dataset_list_ptr dataset, const char *prop, boolean_t received);
+int dataset_prop_valid(dataset_list_ptr dataset, zfs_prop_t prop);
 int dataset_clone(dataset_list_ptr dataset, const char *target, nvlist_ptr props);
 int dataset_snapshot(const char *path, boolean_t recur, nvlist_ptr props);
+int dataset_snapshot_many(nvlist_ptr snaps, nvlist_ptr props, nvlist_ptr *errlist);
+int dataset_destroy_snaps(nvlist_ptr snaps, boolean_t defer, nvlist_ptr *errlist);
+int dataset_snaprange_space(const char *first, const char *last, uint64_t *used);
+int dataset_hold_many(nvlist_ptr holds, int cleanup_fd, nvlist_ptr *errlist);
+int dataset_release_many(nvlist_ptr holds, nvlist_ptr *errlist);
 int dataset_rollback(dataset_list_ptr dataset, dataset_list_ptr snapshot, boolean_t force);
+int dataset_bookmark(const char *source, const char *bookmark);
+int dataset_send_one(dataset_list_ptr snapshot, const char *from, int fd, sendflags_t *flags);
 int dataset_promote(dataset_list_ptr dataset);
 int dataset_rename(dataset_list_ptr dataset, const char* new_name, boolean_t recur, boolean_t force_unm);
 const char* dataset_is_mounted(dataset_list_ptr dataset);
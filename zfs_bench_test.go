@@ -0,0 +1,71 @@
+package zfs_test
+
+import (
+	"fmt"
+	"testing"
+
+	zfs "github.com/bicomsystems/go-libzfs"
+)
+
+const benchSnapshots = 500
+
+// BenchmarkDatasetOpen creates pool with dataset having benchSnapshots
+// snapshots once, and measures opening of the dataset with its snapshots
+// loading all, none or selected properties
+func BenchmarkDatasetOpen(b *testing.B) {
+	pname := TSTPoolName + "_BENCH"
+	path, err := CreateTmpSparse("zfs_test_", 0x40000000)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer removeVDisk(path)
+	vdev, err := zfs.ParseVDevSpec(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	fsprops := make(map[zfs.Prop]string)
+	fsprops[zfs.DatasetPropMountpoint] = "none"
+	pool, err := zfs.PoolCreate(pname, vdev, make(map[string]string),
+		make(map[zfs.Prop]string), fsprops)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer pool.Close()
+	defer pool.Destroy(pname)
+
+	props := make(map[zfs.Prop]zfs.Property)
+	for i := 0; i < benchSnapshots; i++ {
+		s, err := zfs.DatasetSnapshot(fmt.Sprintf("%s@snap%d", pname, i), false, props)
+		if err != nil {
+			b.Fatal(err)
+		}
+		s.Close()
+	}
+
+	// sub-benchmarks are run for every b.N, setup above is done only once
+	benchmarks := []struct {
+		name string
+		opts *zfs.DatasetOpenOptions
+	}{
+		{"AllProps", nil},
+		{"NoProps", &zfs.DatasetOpenOptions{Props: []zfs.Prop{}}},
+		{"SelectedProps", &zfs.DatasetOpenOptions{
+			Props: []zfs.Prop{zfs.DatasetPropCreation, zfs.DatasetPropUsed}}},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				d, err := zfs.DatasetOpenWithOptions(pname, bm.opts)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(d.Children) != benchSnapshots {
+					b.Fatalf("Opened %d snapshots, expected %d", len(d.Children), benchSnapshots)
+				}
+				d.Close()
+			}
+			b.StopTimer()
+		})
+	}
+}
@@ -0,0 +1,333 @@
+package zfs
+
+// #include <stdlib.h>
+// #include <errno.h>
+// #include <libzfs.h>
+// #include "common.h"
+// #include "zpool.h"
+// #include "zfs.h"
+import "C"
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+// KeyFormat format of dataset encryption key, as in keyformat property
+type KeyFormat string
+
+// Encryption key formats
+const (
+	KeyFormatRaw        KeyFormat = "raw"        // 32 bytes
+	KeyFormatHex        KeyFormat = "hex"        // 64 hexadecimal characters
+	KeyFormatPassphrase KeyFormat = "passphrase" // 8 to 512 bytes
+)
+
+const (
+	wrappingKeyLen     = 32
+	minPassphraseLen   = 8
+	maxPassphraseLen   = 512
+	minPBKDF2Iters     = 100000
+	defaultPBKDF2Iters = 350000
+)
+
+// DatasetKey encryption key material supplied from memory, for
+// DatasetCreate and ChangeKey
+type DatasetKey struct {
+	Format     KeyFormat
+	Key        []byte
+	Location   string // keylocation property, unchanged if empty
+	Iterations uint64 // PBKDF2 iterations for passphrase, 350000 if 0
+}
+
+// wrappingKey derives wrapping key from key material the way libzfs does,
+// salt and iters are used only for passphrase
+func (key *DatasetKey) wrappingKey(salt, iters uint64) (wkey []byte, err error) {
+	switch key.Format {
+	case KeyFormatRaw:
+		if len(key.Key) != wrappingKeyLen {
+			err = fmt.Errorf("Raw key must be %d bytes long", wrappingKeyLen)
+			return
+		}
+		wkey = append([]byte(nil), key.Key...)
+	case KeyFormatHex:
+		if len(key.Key) != 2*wrappingKeyLen {
+			err = fmt.Errorf("Hex key must be %d characters long", 2*wrappingKeyLen)
+			return
+		}
+		wkey = make([]byte, wrappingKeyLen)
+		if _, err = hex.Decode(wkey, key.Key); err != nil {
+			zeroKey(wkey)
+			wkey = nil
+			err = fmt.Errorf("Invalid hex key: %s", err.Error())
+		}
+	case KeyFormatPassphrase:
+		if len(key.Key) < minPassphraseLen || len(key.Key) > maxPassphraseLen {
+			err = fmt.Errorf("Passphrase must be %d to %d bytes long",
+				minPassphraseLen, maxPassphraseLen)
+			return
+		}
+		// libzfs uses salt as it is in memory, in native byte order
+		s := (*[8]byte)(unsafe.Pointer(&salt))
+		wkey = pbkdf2SHA1(key.Key, s[:], int(iters), wrappingKeyLen)
+	default:
+		err = fmt.Errorf("Invalid key format '%s'", key.Format)
+	}
+	return
+}
+
+// setProps sets key properties of new encryption root and returns wrapping
+// key derived with new random salt. Keylocation is set only if Location is
+// not empty.
+func (key *DatasetKey) setProps(props map[Prop]Property) (wkey []byte, err error) {
+	var salt, iters uint64
+	props[DatasetPropKeyFormat] = Property{Value: string(key.Format)}
+	if len(key.Location) > 0 {
+		props[DatasetPropKeyLocation] = Property{Value: key.Location}
+	}
+	if key.Format == KeyFormatPassphrase {
+		if iters = key.Iterations; iters == 0 {
+			iters = defaultPBKDF2Iters
+		} else if iters < minPBKDF2Iters {
+			err = fmt.Errorf("PBKDF2 iterations must be at least %d", minPBKDF2Iters)
+			return
+		}
+		if _, err = rand.Read((*[8]byte)(unsafe.Pointer(&salt))[:]); err != nil {
+			return
+		}
+		props[DatasetPropPBKDF2Salt] = Property{Value: strconv.FormatUint(salt, 10)}
+		props[DatasetPropPBKDF2Iters] = Property{Value: strconv.FormatUint(iters, 10)}
+	}
+	return key.wrappingKey(salt, iters)
+}
+
+// zeroKey overwrites key material no longer needed
+func zeroKey(key []byte) {
+	for i := range key {
+		key[i] = 0
+	}
+}
+
+// pbkdf2SHA1 PBKDF2 key derivation (RFC 8018) with HMAC-SHA1, as used by
+// libzfs for passphrases
+func pbkdf2SHA1(password, salt []byte, iter, keyLen int) []byte {
+	prf := hmac.New(sha1.New, password)
+	hashLen := prf.Size()
+	blocks := (keyLen + hashLen - 1) / hashLen
+	dk := make([]byte, 0, blocks*hashLen)
+	u := make([]byte, hashLen)
+	var counter [4]byte
+	for block := 1; block <= blocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(counter[:], uint32(block))
+		prf.Write(counter[:])
+		dk = prf.Sum(dk)
+		t := dk[len(dk)-hashLen:]
+		copy(u, t)
+		for n := 2; n <= iter; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for i := range u {
+				t[i] ^= u[i]
+			}
+		}
+	}
+	zeroKey(u)
+	zeroKey(dk[keyLen:])
+	return dk[:keyLen]
+}
+
+// datasetCreateEncrypted creates new filesystem or volume as encryption root
+// with key material from memory, see DatasetCreate
+func datasetCreateEncrypted(path string, dtype DatasetType,
+	props map[Prop]Property, key DatasetKey) (d Dataset, err error) {
+	var cprops C.nvlist_ptr
+	var wkey []byte
+	cryptprops := make(map[Prop]Property)
+	for p, v := range props {
+		cryptprops[p] = v
+	}
+	if _, ok := cryptprops[DatasetPropEncryption]; !ok {
+		cryptprops[DatasetPropEncryption] = Property{Value: "on"}
+	}
+	if _, ok := cryptprops[DatasetPropKeyLocation]; !ok && len(key.Location) == 0 {
+		cryptprops[DatasetPropKeyLocation] = Property{Value: "prompt"}
+	}
+	if wkey, err = key.setProps(cryptprops); err != nil {
+		return
+	}
+	defer zeroKey(wkey)
+	if cprops, err = datasetPropertiesTonvlist(cryptprops); err != nil {
+		return
+	}
+	defer C.nvlist_free(cprops)
+
+	csPath := C.CString(path)
+	defer C.free(unsafe.Pointer(csPath))
+	switch errcode := C.dataset_create_crypt(csPath, C.zfs_type_t(dtype), cprops,
+		(*C.uint8_t)(unsafe.Pointer(&wkey[0])), C.uint_t(len(wkey))); errcode {
+	case 0:
+	case -1:
+		err = LastError()
+		return
+	default:
+		err = fmt.Errorf("Failed to create '%s': %s", path, syscall.Errno(errcode))
+		return
+	}
+	return DatasetOpen(path)
+}
+
+// IsEncryptionRoot returns true if dataset is encryption root, dataset
+// whose key is loaded, unloaded or changed for all datasets inheriting it
+func (d *Dataset) IsEncryptionRoot() (ok bool) {
+	root := d.propertyValue(DatasetPropEncryptionRoot)
+	ok = len(root) > 0 && root == d.Properties[DatasetPropName].Value
+	return
+}
+
+// LoadKey loads encryption key of encryption root. Key is in format set by
+// keyformat property of the dataset. If key is nil it is read from file
+// or URI set by keylocation property, keylocation prompt requires key.
+func (d *Dataset) LoadKey(key []byte) (err error) {
+	if d.list == nil {
+		err = errors.New(msgDatasetIsNil)
+		return
+	}
+	if key == nil {
+		var location Property
+		if location, err = d.GetProperty(DatasetPropKeyLocation); err != nil {
+			return
+		}
+		if location.Value == "prompt" {
+			err = fmt.Errorf("Key of '%s' is read from prompt, it must be supplied",
+				d.Properties[DatasetPropName].Value)
+			return
+		}
+		if errc := C.dataset_load_key(d.list, nil, 0); errc != 0 {
+			err = LastError()
+			return
+		}
+		return d.ReloadProperties()
+	}
+	dk := DatasetKey{
+		Format: KeyFormat(d.propertyValue(DatasetPropKeyFormat)),
+		Key:    key,
+	}
+	var salt, iters uint64
+	if dk.Format == KeyFormatPassphrase {
+		if salt, err = strconv.ParseUint(
+			d.propertyValue(DatasetPropPBKDF2Salt), 10, 64); err != nil {
+			return
+		}
+		if iters, err = strconv.ParseUint(
+			d.propertyValue(DatasetPropPBKDF2Iters), 10, 64); err != nil {
+			return
+		}
+	}
+	wkey, err := dk.wrappingKey(salt, iters)
+	if err != nil {
+		return
+	}
+	defer zeroKey(wkey)
+	switch errc := C.dataset_load_key(d.list,
+		(*C.uint8_t)(unsafe.Pointer(&wkey[0])), C.uint_t(len(wkey))); errc {
+	case 0:
+	case C.EACCES:
+		err = fmt.Errorf("Incorrect key provided for '%s'",
+			d.Properties[DatasetPropName].Value)
+		return
+	case C.EEXIST:
+		err = fmt.Errorf("Key already loaded for '%s'",
+			d.Properties[DatasetPropName].Value)
+		return
+	default:
+		err = fmt.Errorf("Failed to load key for '%s': %s",
+			d.Properties[DatasetPropName].Value, syscall.Errno(errc))
+		return
+	}
+	return d.ReloadProperties()
+}
+
+// UnloadKey unloads encryption key of encryption root. All datasets using
+// the key must be unmounted.
+func (d *Dataset) UnloadKey() (err error) {
+	if d.list == nil {
+		err = errors.New(msgDatasetIsNil)
+		return
+	}
+	if errc := C.zfs_crypto_unload_key(d.list.zh); errc != 0 {
+		err = LastError()
+		return
+	}
+	return d.ReloadProperties()
+}
+
+// ChangeKey changes wrapping key of encrypted dataset, making it encryption
+// root if it inherits key of its parent. Current key must be loaded. Data
+// is not re-encrypted, only the master key is wrapped with the new key.
+// Keylocation is kept if key.Location is empty, new encryption root gets
+// prompt.
+func (d *Dataset) ChangeKey(key DatasetKey) (err error) {
+	if d.list == nil {
+		err = errors.New(msgDatasetIsNil)
+		return
+	}
+	var cprops C.nvlist_ptr
+	props := make(map[Prop]Property)
+	if len(key.Location) == 0 && !d.IsEncryptionRoot() {
+		props[DatasetPropKeyLocation] = Property{Value: "prompt"}
+	}
+	wkey, err := key.setProps(props)
+	if err != nil {
+		return
+	}
+	defer zeroKey(wkey)
+	if cprops, err = datasetPropertiesTonvlist(props); err != nil {
+		return
+	}
+	defer C.nvlist_free(cprops)
+	err = d.changeKey(cprops, wkey)
+	return
+}
+
+// InheritKey makes encryption root inherit key of its parent encryption
+// root, the dataset stops being encryption root. Keys of both must be loaded.
+func (d *Dataset) InheritKey() (err error) {
+	if d.list == nil {
+		err = errors.New(msgDatasetIsNil)
+		return
+	}
+	err = d.changeKey(nil, nil)
+	return
+}
+
+func (d *Dataset) changeKey(cprops C.nvlist_ptr, wkey []byte) (err error) {
+	var cwkey *C.uint8_t
+	if len(wkey) > 0 {
+		cwkey = (*C.uint8_t)(unsafe.Pointer(&wkey[0]))
+	}
+	switch errc := C.dataset_change_key(d.list, cprops, cwkey, C.uint_t(len(wkey))); errc {
+	case 0:
+	case -1:
+		err = LastError()
+		return
+	case C.EACCES:
+		err = fmt.Errorf("Key must be loaded to change key of '%s'",
+			d.Properties[DatasetPropName].Value)
+		return
+	default:
+		err = fmt.Errorf("Failed to change key of '%s': %s",
+			d.Properties[DatasetPropName].Value, syscall.Errno(errc))
+		return
+	}
+	return d.ReloadProperties()
+}
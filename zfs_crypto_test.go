@@ -0,0 +1,111 @@
+package zfs_test
+
+import (
+	"testing"
+
+	zfs "github.com/bicomsystems/go-libzfs"
+)
+
+func TestDataset_Encryption(t *testing.T) {
+	zpoolTestPoolCreate(t)
+	defer func() {
+		zpoolTestPoolDestroy(t)
+		cleanupVDisks()
+	}()
+	println("TEST Dataset Encryption ... ")
+	props := make(map[zfs.Prop]zfs.Property)
+	rootpath := TSTPoolName + "/encrypted"
+	passphrase := []byte("test passphrase")
+	key := zfs.DatasetKey{Format: zfs.KeyFormatPassphrase, Key: passphrase}
+
+	if _, err := zfs.DatasetCreate(rootpath, zfs.DatasetTypeFilesystem, props,
+		key, key); err == nil {
+		t.Error("Expected error creating dataset with two keys")
+		return
+	}
+	d, err := zfs.DatasetCreate(rootpath, zfs.DatasetTypeFilesystem, props, key)
+	if err != nil {
+		t.Errorf("DatasetCreate(\"%s\") error: %v", rootpath, err)
+		return
+	}
+	defer d.Close()
+	if !d.IsEncryptionRoot() {
+		t.Errorf("%s is not encryption root", rootpath)
+		return
+	}
+	// properties are loaded on demand if not loaded on open
+	lazy, err := zfs.DatasetOpenWithOptions(rootpath,
+		&zfs.DatasetOpenOptions{Props: []zfs.Prop{}})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	ok := lazy.IsEncryptionRoot()
+	lazy.Close()
+	if !ok {
+		t.Errorf("%s opened without properties is not encryption root", rootpath)
+		return
+	}
+	if err = d.UnloadKey(); err != nil {
+		t.Error(err)
+		return
+	}
+	if err = d.LoadKey(nil); err == nil {
+		t.Error("LoadKey without key succeeded with keylocation prompt")
+		return
+	}
+	if err = d.LoadKey([]byte("wrong passphrase")); err == nil {
+		t.Error("LoadKey succeeded with wrong passphrase")
+		return
+	}
+	if err = d.LoadKey(passphrase); err != nil {
+		t.Error(err)
+		return
+	}
+	if d.Properties[zfs.DatasetPropKeyStatus].Value != "available" {
+		t.Errorf("Key of %s not loaded: %s", rootpath,
+			d.Properties[zfs.DatasetPropKeyStatus].Value)
+		return
+	}
+
+	childpath := rootpath + "/child"
+	c, err := zfs.DatasetCreate(childpath, zfs.DatasetTypeFilesystem, props)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer c.Close()
+	if c.IsEncryptionRoot() {
+		t.Errorf("%s should inherit key of %s", childpath, rootpath)
+		return
+	}
+	hexkey := []byte("0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef")
+	location := "file:///nonexistent/" + TSTPoolName + ".key"
+	if err = c.ChangeKey(zfs.DatasetKey{Format: zfs.KeyFormatHex, Key: hexkey,
+		Location: location}); err != nil {
+		t.Error(err)
+		return
+	}
+	if !c.IsEncryptionRoot() {
+		t.Errorf("%s is not encryption root after ChangeKey", childpath)
+		return
+	}
+	rawkey := []byte("0123456789abcdef0123456789abcdef")
+	if err = c.ChangeKey(zfs.DatasetKey{Format: zfs.KeyFormatRaw, Key: rawkey}); err != nil {
+		t.Error(err)
+		return
+	}
+	if v := c.Properties[zfs.DatasetPropKeyLocation].Value; v != location {
+		t.Errorf("ChangeKey without location changed keylocation to %s", v)
+		return
+	}
+	if err = c.InheritKey(); err != nil {
+		t.Error(err)
+		return
+	}
+	if c.IsEncryptionRoot() {
+		t.Errorf("%s is still encryption root after InheritKey", childpath)
+		return
+	}
+	print("PASS\n\n")
+}
@@ -0,0 +1,167 @@
+package zfs
+
+// #include <stdlib.h>
+// #include <libzfs.h>
+// #include "common.h"
+// #include "zpool.h"
+// #include "zfs.h"
+import "C"
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// SkipDataset returned by IterDatasets callback skips descendants of the
+// dataset
+var SkipDataset = errors.New("skip this dataset")
+
+// DatasetIterOptions options of IterDatasets
+type DatasetIterOptions struct {
+	// Types mask of dataset types passed to callback, all types if 0.
+	// Snapshots and bookmarks not included are not opened at all.
+	Types DatasetType
+	// MaxDepth maximum depth of descendants below root, 1 for children of
+	// root only, unlimited if 0
+	MaxDepth int
+	// Props properties loaded, see DatasetOpenOptions
+	Props []Prop
+	// Filter predicate datasets passed to callback must match, descendants
+	// of datasets not matching are still iterated
+	Filter func(d *Dataset) bool
+	// Less order of siblings, see SortByProperty. Siblings are opened all
+	// at once to be sorted. Order of libzfs iteration if nil.
+	Less func(a, b *Dataset) bool
+}
+
+// IterDatasets calls fn for root dataset and all of its descendants, depth
+// first, opening only datasets of one branch at a time instead of whole
+// tree, and without Less one sibling at a time. Dataset passed to fn is closed after fn returns and has no Children.
+// Return SkipDataset from fn to skip descendants of the dataset, or any
+// other error to stop iteration and return that error.
+func IterDatasets(root string, opts *DatasetIterOptions,
+	fn func(d *Dataset) error) (err error) {
+	if opts == nil {
+		opts = &DatasetIterOptions{}
+	}
+	d, err := datasetOpenSingle(root, &DatasetOpenOptions{Props: opts.Props})
+	if err != nil {
+		return
+	}
+	defer d.Close()
+	err = opts.iter(&d, 0, fn)
+	return
+}
+
+func (opts *DatasetIterOptions) match(d *Dataset) bool {
+	if opts.Types != 0 && opts.Types&d.Type == 0 {
+		return false
+	}
+	return opts.Filter == nil || opts.Filter(d)
+}
+
+func (opts *DatasetIterOptions) iter(d *Dataset, depth int,
+	fn func(d *Dataset) error) (err error) {
+	if opts.match(d) {
+		if err = fn(d); err == SkipDataset {
+			return nil
+		} else if err != nil {
+			return
+		}
+	}
+	if opts.MaxDepth > 0 && depth >= opts.MaxDepth {
+		return
+	}
+	if opts.Less == nil {
+		return opts.iterChildren(d, depth, fn)
+	}
+	children, err := opts.children(d)
+	defer DatasetCloseAll(children)
+	if err != nil {
+		return
+	}
+	for i := range children {
+		if err = opts.iter(&children[i], depth+1, fn); err != nil {
+			return
+		}
+		children[i].Close()
+	}
+	return
+}
+
+// iterChildren iterates children of dataset in order of libzfs iteration,
+// opening one child at a time
+func (opts *DatasetIterOptions) iterChildren(d *Dataset, depth int,
+	fn func(d *Dataset) error) (err error) {
+	names := C.dataset_children_names(d.list, C.int(opts.childTypes()))
+	if names == nil {
+		return
+	}
+	defer C.nvlist_free(names)
+	oopts := &DatasetOpenOptions{Props: opts.Props}
+	for nvp := C.nvlist_next_nvpair(names, nil); nvp != nil; nvp = C.nvlist_next_nvpair(names, nvp) {
+		child, e := datasetOpenSingle(C.GoString(C.nvpair_name(nvp)), oopts)
+		if e != nil {
+			// destroyed since listed
+			continue
+		}
+		err = opts.iter(&child, depth+1, fn)
+		child.Close()
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// childTypes types of children listed for iteration, filesystems and
+// volumes are always listed
+func (opts *DatasetIterOptions) childTypes() DatasetType {
+	if opts.Types == 0 {
+		return DatasetTypeSnapshot | DatasetTypeBookmark
+	}
+	return opts.Types
+}
+
+// children opens all children of dataset with types needed for iteration,
+// sorted by Less
+func (opts *DatasetIterOptions) children(d *Dataset) (children []Dataset, err error) {
+	oopts := &DatasetOpenOptions{Props: opts.Props}
+	list := C.dataset_list_children_types(d.list, C.int(opts.childTypes()))
+	for list != nil {
+		child := Dataset{list: list, closeOnce: new(sync.Once)}
+		child.Type = DatasetType(C.dataset_type(list))
+		children = append(children, child)
+		list = C.dataset_next(list)
+	}
+	for i := range children {
+		if err = children[i].loadOpenProperties(oopts); err != nil {
+			return
+		}
+	}
+	if opts.Less != nil {
+		sort.SliceStable(children, func(i, j int) bool {
+			return opts.Less(&children[i], &children[j])
+		})
+	}
+	return
+}
+
+// SortByProperty returns Less function of DatasetIterOptions ordering
+// datasets by value of property, numerically if values are numbers.
+// Property is loaded if not loaded on open.
+func SortByProperty(p Prop, desc bool) func(a, b *Dataset) bool {
+	return func(a, b *Dataset) bool {
+		va, vb := a.propertyValue(p), b.propertyValue(p)
+		if desc {
+			va, vb = vb, va
+		}
+		na, erra := strconv.ParseUint(va, 10, 64)
+		nb, errb := strconv.ParseUint(vb, 10, 64)
+		if erra == nil && errb == nil {
+			return na < nb
+		}
+		return va < vb
+	}
+}
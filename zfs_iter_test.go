@@ -0,0 +1,91 @@
+package zfs_test
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	zfs "github.com/bicomsystems/go-libzfs"
+)
+
+func TestIterDatasets(t *testing.T) {
+	zpoolTestPoolCreate(t)
+	defer func() {
+		zpoolTestPoolDestroy(t)
+		cleanupVDisks()
+	}()
+	println("TEST IterDatasets ... ")
+	props := make(map[zfs.Prop]zfs.Property)
+	root := TSTPoolName + "/iter"
+	for _, name := range []string{root, root + "/b", root + "/a", root + "/a/c"} {
+		d, err := zfs.DatasetCreate(name, zfs.DatasetTypeFilesystem, props)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		d.Close()
+	}
+	for _, name := range []string{root + "@s1", root + "/a@s2"} {
+		s, err := zfs.DatasetSnapshot(name, false, props)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		s.Close()
+	}
+
+	iter := func(opts *zfs.DatasetIterOptions, skip string) (names []string, err error) {
+		err = zfs.IterDatasets(root, opts, func(d *zfs.Dataset) error {
+			name := d.Properties[zfs.DatasetPropName].Value
+			names = append(names, name)
+			if name == skip {
+				return zfs.SkipDataset
+			}
+			return nil
+		})
+		return
+	}
+	byName := zfs.SortByProperty(zfs.DatasetPropName, false)
+	tests := []struct {
+		opts zfs.DatasetIterOptions
+		skip string
+		want []string
+	}{
+		{zfs.DatasetIterOptions{Types: zfs.DatasetTypeFilesystem, Less: byName}, "",
+			[]string{root, root + "/a", root + "/a/c", root + "/b"}},
+		{zfs.DatasetIterOptions{Types: zfs.DatasetTypeSnapshot, Less: byName}, "",
+			[]string{root + "/a@s2", root + "@s1"}},
+		{zfs.DatasetIterOptions{Types: zfs.DatasetTypeFilesystem, MaxDepth: 1,
+			Props: []zfs.Prop{}, Less: byName}, "",
+			[]string{root, root + "/a", root + "/b"}},
+		{zfs.DatasetIterOptions{Types: zfs.DatasetTypeFilesystem, Less: byName}, root + "/a",
+			[]string{root, root + "/a", root + "/b"}},
+		{zfs.DatasetIterOptions{Less: byName, Filter: func(d *zfs.Dataset) bool {
+			return d.IsSnapshot()
+		}}, "", []string{root + "/a@s2", root + "@s1"}},
+	}
+	for _, tt := range tests {
+		names, err := iter(&tt.opts, tt.skip)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if !reflect.DeepEqual(names, tt.want) {
+			t.Errorf("IterDatasets() = %v, want %v", names, tt.want)
+		}
+	}
+
+	// without Less siblings are opened one at a time in libzfs order
+	names, err := iter(&zfs.DatasetIterOptions{}, root+"/b")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	sort.Strings(names)
+	want := []string{root, root + "/a", root + "/a/c", root + "/a@s2",
+		root + "/b", root + "@s1"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("IterDatasets() without Less = %v, want %v", names, want)
+	}
+	print("PASS\n\n")
+}
@@ -0,0 +1,178 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ErrKeyNotFound returned by KeyProvider that has no key for dataset
+var ErrKeyNotFound = errors.New("Encryption key not found")
+
+// KeyProvider supplies encryption keys of encryption roots, e.g. from
+// secrets manager, to LoadKeyFrom, MountWithKey and LoadAllKeys. Key is
+// in format set by keyformat property of the dataset, one trailing newline
+// is ignored for hex and passphrase keys as libzfs does for key files.
+type KeyProvider interface {
+	Key(ctx context.Context, dataset string) ([]byte, error)
+}
+
+// FileKeyProvider reads key of dataset from file in Dir with path of the
+// dataset name, e.g. <Dir>/pool/encrypted for pool/encrypted
+type FileKeyProvider struct {
+	Dir string
+}
+
+// Key implements KeyProvider
+func (p FileKeyProvider) Key(ctx context.Context, dataset string) (key []byte, err error) {
+	if key, err = ioutil.ReadFile(filepath.Join(p.Dir, dataset)); os.IsNotExist(err) {
+		err = ErrKeyNotFound
+	}
+	return
+}
+
+// MemoryKeyProvider keeps keys in memory, safe for concurrent use. Zero
+// value is empty provider ready to use.
+type MemoryKeyProvider struct {
+	mtx  sync.RWMutex
+	keys map[string][]byte
+}
+
+// Set sets key of dataset
+func (p *MemoryKeyProvider) Set(dataset string, key []byte) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if p.keys == nil {
+		p.keys = make(map[string][]byte)
+	}
+	p.keys[dataset] = append([]byte(nil), key...)
+}
+
+// Delete removes key of dataset
+func (p *MemoryKeyProvider) Delete(dataset string) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	delete(p.keys, dataset)
+}
+
+// Key implements KeyProvider
+func (p *MemoryKeyProvider) Key(ctx context.Context, dataset string) (key []byte, err error) {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+	k, ok := p.keys[dataset]
+	if !ok {
+		err = ErrKeyNotFound
+		return
+	}
+	key = append([]byte(nil), k...)
+	return
+}
+
+// keyUnavailable returns true if dataset is encryption root with key not
+// loaded
+func (d *Dataset) keyUnavailable() bool {
+	return d.IsEncryptionRoot() &&
+		d.propertyValue(DatasetPropKeyStatus) == "unavailable"
+}
+
+// loadProviderKey loads key of encryption root from provider
+func (d *Dataset) loadProviderKey(ctx context.Context, provider KeyProvider) (err error) {
+	name := d.Properties[DatasetPropName].Value
+	key, err := provider.Key(ctx, name)
+	if err != nil {
+		err = fmt.Errorf("Failed to get key for '%s': %s", name, err.Error())
+		return
+	}
+	format := KeyFormat(d.propertyValue(DatasetPropKeyFormat))
+	if format != KeyFormatRaw && len(key) > 0 && key[len(key)-1] == '\n' {
+		key = key[:len(key)-1]
+	}
+	return d.LoadKey(key)
+}
+
+// LoadKeyFrom loads key of dataset's encryption root from provider, if it
+// is not already loaded. Does nothing for datasets that are not encrypted.
+func (d *Dataset) LoadKeyFrom(ctx context.Context, provider KeyProvider) (err error) {
+	if d.list == nil {
+		err = errors.New(msgDatasetIsNil)
+		return
+	}
+	if err = d.ReloadProperties(); err != nil {
+		return
+	}
+	root := d.Properties[DatasetPropEncryptionRoot].Value
+	if len(root) == 0 || d.Properties[DatasetPropKeyStatus].Value != "unavailable" {
+		return
+	}
+	if d.IsEncryptionRoot() {
+		return d.loadProviderKey(ctx, provider)
+	}
+	rd, err := DatasetOpenSingle(root)
+	if err != nil {
+		return
+	}
+	defer rd.Close()
+	if err = rd.loadProviderKey(ctx, provider); err != nil {
+		return
+	}
+	return d.ReloadProperties()
+}
+
+// MountWithKey loads key of encrypted dataset from provider if needed and
+// mounts the dataset, see Mount
+func (d *Dataset) MountWithKey(ctx context.Context, provider KeyProvider,
+	options string, flags int) (err error) {
+	if err = d.LoadKeyFrom(ctx, provider); err != nil {
+		return
+	}
+	return d.Mount(options, flags)
+}
+
+// LoadAllKeys loads keys of all encryption roots in pool, or under dataset
+// with given name, whose keys are not loaded, like zfs load-key -r. Loading
+// continues after failure, returns names of encryption roots whose keys
+// are loaded and error of the first failure.
+func LoadAllKeys(ctx context.Context, name string, provider KeyProvider) (loaded []string, err error) {
+	d, err := DatasetOpen(name)
+	if err != nil {
+		return
+	}
+	defer d.Close()
+	var roots []*Dataset
+	var collect func(ds *Dataset)
+	collect = func(ds *Dataset) {
+		if ds.keyUnavailable() {
+			roots = append(roots, ds)
+		}
+		for i := range ds.Children {
+			if ch := &ds.Children[i]; !ch.IsSnapshot() && !ch.IsBookmark() {
+				collect(ch)
+			}
+		}
+	}
+	collect(&d)
+	failed := 0
+	var first error
+	for _, root := range roots {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		if e := root.loadProviderKey(ctx, provider); e != nil {
+			if first == nil {
+				first = e
+			}
+			failed++
+			continue
+		}
+		loaded = append(loaded, root.Properties[DatasetPropName].Value)
+	}
+	if failed > 0 {
+		err = fmt.Errorf("Failed to load %d of %d keys: %s", failed, len(roots),
+			first.Error())
+	}
+	return
+}
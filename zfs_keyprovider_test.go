@@ -0,0 +1,90 @@
+package zfs_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	zfs "github.com/bicomsystems/go-libzfs"
+)
+
+func TestMemoryKeyProvider(t *testing.T) {
+	var p zfs.MemoryKeyProvider
+	ctx := context.Background()
+	if _, err := p.Key(ctx, "pool/fs"); err != zfs.ErrKeyNotFound {
+		t.Errorf("Key of empty provider: %v", err)
+	}
+	p.Set("pool/fs", []byte("passphrase"))
+	key, err := p.Key(ctx, "pool/fs")
+	if err != nil || string(key) != "passphrase" {
+		t.Errorf("Key() = %q, %v", key, err)
+	}
+	p.Delete("pool/fs")
+	if _, err = p.Key(ctx, "pool/fs"); err != zfs.ErrKeyNotFound {
+		t.Errorf("Key after Delete: %v", err)
+	}
+}
+
+func TestFileKeyProvider(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zfs_keys_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err = os.MkdirAll(filepath.Join(dir, "pool"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(dir, "pool", "fs"),
+		[]byte("passphrase\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	p := zfs.FileKeyProvider{Dir: dir}
+	key, err := p.Key(context.Background(), "pool/fs")
+	if err != nil || string(key) != "passphrase\n" {
+		t.Errorf("Key() = %q, %v", key, err)
+	}
+	if _, err = p.Key(context.Background(), "pool/other"); err != zfs.ErrKeyNotFound {
+		t.Errorf("Key of missing file: %v", err)
+	}
+}
+
+func TestLoadAllKeys(t *testing.T) {
+	zpoolTestPoolCreate(t)
+	defer func() {
+		zpoolTestPoolDestroy(t)
+		cleanupVDisks()
+	}()
+	println("TEST LoadAllKeys ... ")
+	props := make(map[zfs.Prop]zfs.Property)
+	var provider zfs.MemoryKeyProvider
+	roots := []string{TSTPoolName + "/enc1", TSTPoolName + "/enc2"}
+	for _, name := range roots {
+		key := []byte("passphrase of " + name)
+		d, err := zfs.DatasetCreate(name, zfs.DatasetTypeFilesystem, props,
+			zfs.DatasetKey{Format: zfs.KeyFormatPassphrase, Key: key})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		err = d.UnloadKey()
+		d.Close()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		provider.Set(name, key)
+	}
+
+	loaded, err := zfs.LoadAllKeys(context.Background(), TSTPoolName, &provider)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(loaded) != len(roots) {
+		t.Errorf("Loaded keys of %v, expected %v", loaded, roots)
+		return
+	}
+	print("PASS\n\n")
+}
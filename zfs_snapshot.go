@@ -0,0 +1,524 @@
+package zfs
+
+// #include <stdlib.h>
+// #include <libzfs.h>
+// #include "common.h"
+// #include "zpool.h"
+// #include "zfs.h"
+import "C"
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// DestroySnapshotsOptions options of DestroySnapshots
+type DestroySnapshotsOptions struct {
+	// Defer marks snapshots with holds or clones for deferred destroy
+	// instead of failing, like zfs destroy -d
+	Defer bool
+	// Recursive destroys snapshots matching spec of all descendants too,
+	// like zfs destroy -r
+	Recursive bool
+	// DryRun only finds snapshots and estimates space they would free
+	DryRun bool
+}
+
+// DestroySnapshotsResult snapshots destroyed by DestroySnapshots, or to be
+// destroyed in dry run
+type DestroySnapshotsResult struct {
+	// Snapshots full names, oldest first within each dataset
+	Snapshots []string
+	// Reclaimed space freed by destroying only that snapshot, by snapshot
+	// name, dry run only
+	Reclaimed map[string]uint64
+	// Total space freed by destroying all snapshots, dry run only
+	Total uint64
+}
+
+// DestroyErrors errors of individual snapshots by snapshot name, returned
+// by DestroySnapshots
+type DestroyErrors map[string]error
+
+func (e DestroyErrors) Error() string {
+	return formatNamedErrors("Failed to destroy snapshots", e)
+}
+
+// DestroySnapshots destroys snapshots of dataset matching spec at once, in
+// single transaction group. Spec is comma separated list of snapshot names
+// and ranges, as in zfs destroy pool/fs@a%b,c, where range a%b includes
+// all snapshots from a to b, and missing a or b means oldest or newest
+// snapshot. Snapshots in spec not found are ignored, but at least one
+// snapshot has to match. With Recursive, descendants whose snapshots of a
+// range are in reverse order are skipped. If some of snapshots can't be
+// destroyed none are, and DestroyErrors with error of each of them is
+// returned.
+func DestroySnapshots(dataset string, spec string,
+	opts *DestroySnapshotsOptions) (res DestroySnapshotsResult, err error) {
+	if opts == nil {
+		opts = &DestroySnapshotsOptions{}
+	}
+	if strings.ContainsAny(dataset, "@#") {
+		err = fmt.Errorf("Invalid dataset name '%s', expected filesystem or volume", dataset)
+		return
+	}
+	// snapshot names of each dataset, oldest first
+	snaps := make(map[string][]string)
+	var datasets []string
+	iopts := &DatasetIterOptions{
+		Types: DatasetTypeFilesystem | DatasetTypeVolume | DatasetTypeSnapshot,
+		Props: []Prop{DatasetPropCreateTXG},
+		Less:  SortByProperty(DatasetPropCreateTXG, false),
+	}
+	err = IterDatasets(dataset, iopts, func(d *Dataset) error {
+		name := d.Properties[DatasetPropName].Value
+		if !d.IsSnapshot() {
+			if name != dataset && !opts.Recursive {
+				return SkipDataset
+			}
+			datasets = append(datasets, name)
+			return nil
+		}
+		i := strings.Index(name, "@")
+		snaps[name[:i]] = append(snaps[name[:i]], name[i+1:])
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	// matching snapshots, indexes into snaps, of each dataset
+	matched := make(map[string][]int)
+	for _, ds := range datasets {
+		matched[ds], err = matchSnapshotSpec(snaps[ds], spec)
+		if err == errSnapshotRangeOrder {
+			if ds != dataset {
+				delete(matched, ds)
+				err = nil
+				continue
+			}
+			err = fmt.Errorf("Invalid snapshot range in '%s': %s", spec, err.Error())
+		}
+		if err != nil {
+			return
+		}
+		for _, i := range matched[ds] {
+			res.Snapshots = append(res.Snapshots, ds+"@"+snaps[ds][i])
+		}
+	}
+	if len(res.Snapshots) == 0 {
+		err = fmt.Errorf("Could not find any snapshots of '%s' matching '%s'", dataset, spec)
+		return
+	}
+	if opts.DryRun {
+		res.Reclaimed = make(map[string]uint64)
+		for _, name := range res.Snapshots {
+			if res.Reclaimed[name], err = snaprangeSpace(name, name); err != nil {
+				return
+			}
+		}
+		// space of contiguous ranges, destroying adjacent snapshots frees
+		// also space they only share between them
+		for _, ds := range datasets {
+			idx := matched[ds]
+			for start, end := 0, 0; start < len(idx); start = end {
+				for end = start + 1; end < len(idx) && idx[end] == idx[end-1]+1; end++ {
+				}
+				var used uint64
+				used, err = snaprangeSpace(ds+"@"+snaps[ds][idx[start]],
+					ds+"@"+snaps[ds][idx[end-1]])
+				if err != nil {
+					return
+				}
+				res.Total += used
+			}
+		}
+		return
+	}
+	err = destroySnapshots(res.Snapshots, opts.Defer)
+	return
+}
+
+// errSnapshotRangeOrder first snapshot of range is newer than last
+var errSnapshotRangeOrder = errors.New("first snapshot is newer than last")
+
+// matchSnapshotSpec returns indexes of snapshots, ordered oldest first,
+// matching spec, in the same order
+func matchSnapshotSpec(names []string, spec string) (idx []int, err error) {
+	index := func(name string) int {
+		for i, n := range names {
+			if n == name {
+				return i
+			}
+		}
+		return -1
+	}
+	selected := make([]bool, len(names))
+	for _, item := range strings.Split(spec, ",") {
+		if len(item) == 0 || strings.ContainsAny(item, "@/#") {
+			err = fmt.Errorf("Invalid snapshot spec '%s'", spec)
+			return
+		}
+		i := strings.Index(item, "%")
+		if i < 0 {
+			if j := index(item); j >= 0 {
+				selected[j] = true
+			}
+			continue
+		}
+		first, last := item[:i], item[i+1:]
+		start, end := 0, len(names)-1
+		if len(first) > 0 {
+			start = index(first)
+		}
+		if len(last) > 0 {
+			end = index(last)
+		}
+		if start < 0 || end < 0 {
+			continue
+		}
+		if start > end {
+			err = errSnapshotRangeOrder
+			return
+		}
+		for j := start; j <= end; j++ {
+			selected[j] = true
+		}
+	}
+	for i, ok := range selected {
+		if ok {
+			idx = append(idx, i)
+		}
+	}
+	return
+}
+
+// snaprangeSpace returns space freed by destroying snapshots from first to
+// last, full names, of the same dataset
+func snaprangeSpace(first, last string) (used uint64, err error) {
+	csFirst := C.CString(first)
+	defer C.free(unsafe.Pointer(csFirst))
+	csLast := C.CString(last)
+	defer C.free(unsafe.Pointer(csLast))
+	var cused C.uint64_t
+	if r := C.dataset_snaprange_space(csFirst, csLast, &cused); r != 0 {
+		err = fmt.Errorf("Failed to estimate space of %s to %s: %s", first, last,
+			syscall.Errno(r))
+		return
+	}
+	used = uint64(cused)
+	return
+}
+
+// destroySnapshots destroys snapshots with full names at once
+func destroySnapshots(names []string, deferDestroy bool) (err error) {
+	var errlist C.nvlist_ptr
+	if len(names) == 0 {
+		return
+	}
+	snaps, err := namesToNvlist(names)
+	if err != nil {
+		return
+	}
+	defer C.nvlist_free(snaps)
+	r := C.dataset_destroy_snaps(snaps, booleanT(deferDestroy), &errlist)
+	if errlist != nil {
+		defer C.nvlist_free(errlist)
+	}
+	if r == 0 {
+		return
+	}
+	derrs := DestroyErrors(errlistToMap(errlist))
+	if len(derrs) == 0 {
+		err = fmt.Errorf("Failed to destroy snapshots: %s", syscall.Errno(r))
+		return
+	}
+	for name, e := range derrs {
+		if e == syscall.EEXIST {
+			derrs[name] = errors.New("snapshot is cloned")
+		}
+	}
+	err = derrs
+	return
+}
+
+// HoldErrors errors of individual snapshots by snapshot name, returned by
+// HoldSnapshots
+type HoldErrors map[string]error
+
+func (e HoldErrors) Error() string {
+	return formatNamedErrors("Failed to hold snapshots", e)
+}
+
+// ReleaseErrors errors of individual snapshots by snapshot name, returned
+// by ReleaseSnapshots
+type ReleaseErrors map[string]error
+
+func (e ReleaseErrors) Error() string {
+	return formatNamedErrors("Failed to release snapshots", e)
+}
+
+// HoldCleanupFile opens file which, passed to HoldSnapshots or
+// HoldRecursive, ties lifetime of holds to it. Holds are released when the
+// file is closed, also if process exits without releasing them.
+func HoldCleanupFile() (f *os.File, err error) {
+	return os.OpenFile("/dev/zfs", os.O_RDWR, 0)
+}
+
+// HoldSnapshots adds holds to snapshots of any datasets of the same pool.
+// Holds is map of tag by full snapshot name. If cleanup is not nil holds
+// are released when cleanup is closed, see HoldCleanupFile. Holds of
+// existing snapshots are added atomically, all or none of them, and if
+// some can't be added HoldErrors with error of each is returned. Missing
+// snapshots are skipped and reported as ENOENT in HoldErrors, while holds
+// of the existing ones are still added.
+func HoldSnapshots(holds map[string]string, cleanup *os.File) (err error) {
+	var errlist C.nvlist_ptr
+	if len(holds) == 0 {
+		return
+	}
+	cholds, err := stringsToNvlist(holds)
+	if err != nil {
+		return
+	}
+	defer C.nvlist_free(cholds)
+	fd := -1
+	if cleanup != nil {
+		fd = int(cleanup.Fd())
+	}
+	r := C.dataset_hold_many(cholds, C.int(fd), &errlist)
+	herrs := make(HoldErrors)
+	if errlist != nil {
+		herrs = errlistToMap(errlist)
+		C.nvlist_free(errlist)
+	}
+	// missing snapshots are reported in errlist, even if r is 0
+	if len(herrs) > 0 {
+		err = herrs
+	} else if r != 0 {
+		err = fmt.Errorf("Failed to hold snapshots: %s", syscall.Errno(r))
+	}
+	return
+}
+
+// ReleaseSnapshots removes holds from snapshots of any datasets of the same
+// pool. Holds is map of tags by full snapshot name. Holds of existing
+// snapshots are removed atomically, all or none of them, and if some can't
+// be removed ReleaseErrors with error of each is returned. Missing
+// snapshots are skipped and reported as ENOENT in ReleaseErrors, while
+// holds of the existing ones are still removed.
+func ReleaseSnapshots(holds map[string][]string) (err error) {
+	var cholds, errlist C.nvlist_ptr
+	if len(holds) == 0 {
+		return
+	}
+	if r := C.nvlist_alloc(&cholds, C.NV_UNIQUE_NAME, 0); r != 0 {
+		err = errors.New("Failed to allocate hold list")
+		return
+	}
+	defer C.nvlist_free(cholds)
+	for name, tags := range holds {
+		var ctags C.nvlist_ptr
+		if ctags, err = namesToNvlist(tags); err != nil {
+			return
+		}
+		csName := C.CString(name)
+		r := C.nvlist_add_nvlist(cholds, csName, ctags)
+		C.free(unsafe.Pointer(csName))
+		C.nvlist_free(ctags)
+		if r != 0 {
+			err = fmt.Errorf("Failed to add '%s' to hold list: %s", name,
+				syscall.Errno(r))
+			return
+		}
+	}
+	r := C.dataset_release_many(cholds, &errlist)
+	rerrs := make(ReleaseErrors)
+	if errlist != nil {
+		rerrs = errlistToMap(errlist)
+		C.nvlist_free(errlist)
+	}
+	// missing snapshots are reported in errlist, even if r is 0
+	if len(rerrs) > 0 {
+		err = rerrs
+	} else if r != 0 {
+		err = fmt.Errorf("Failed to release snapshots: %s", syscall.Errno(r))
+	}
+	return
+}
+
+// RollbackOptions options of RollbackTo
+type RollbackOptions struct {
+	// DestroyNewer destroys snapshots and bookmarks more recent than the
+	// snapshot, like zfs rollback -r
+	DestroyNewer bool
+	// DestroyClones destroys also clones of more recent snapshots, like
+	// zfs rollback -R, implies DestroyNewer
+	DestroyClones bool
+	// Force unmounts clones being destroyed, like zfs rollback -f
+	Force bool
+	// Confirmed plan returned by RollbackPreview, if not nil rollback fails
+	// when it would destroy anything else
+	Confirmed *RollbackPlan
+}
+
+// RollbackPlan datasets destroyed by rollback to snapshot, returned by
+// RollbackPreview and RollbackTo
+type RollbackPlan struct {
+	// Snapshots more recent than the snapshot, newest first
+	Snapshots []string
+	// Bookmarks more recent than the snapshot, newest first
+	Bookmarks []string
+	// Clones of more recent snapshots, with all their descendants and
+	// clones of their snapshots, in order they are destroyed
+	Clones []string
+	// Held snapshots of Snapshots and Clones with user holds, they can't be
+	// destroyed so rollback fails if there are any
+	Held []string
+}
+
+// equal returns true if plan destroys the same datasets as other
+func (plan *RollbackPlan) equal(other *RollbackPlan) bool {
+	equal := func(a, b []string) bool {
+		if len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	}
+	return equal(plan.Snapshots, other.Snapshots) &&
+		equal(plan.Bookmarks, other.Bookmarks) &&
+		equal(plan.Clones, other.Clones)
+}
+
+// RollbackPreview returns everything rollback of dataset to snapshot snap
+// would destroy, without changing anything
+func (d *Dataset) RollbackPreview(snap *Dataset) (plan RollbackPlan, err error) {
+	var path, spath string
+	var txg uint64
+	if d.list == nil || snap.list == nil {
+		err = errors.New(msgDatasetIsNil)
+		return
+	}
+	if path, err = d.Path(); err != nil {
+		return
+	}
+	if spath, err = snap.Path(); err != nil {
+		return
+	}
+	if !strings.HasPrefix(spath, path+"@") {
+		err = fmt.Errorf("'%s' is not a snapshot of '%s'", spath, path)
+		return
+	}
+	if err = snap.LoadProperties(DatasetPropCreateTXG); err != nil {
+		return
+	}
+	if txg, err = snap.Properties[DatasetPropCreateTXG].Uint64(); err != nil {
+		return
+	}
+	iopts := &DatasetIterOptions{
+		Types:    DatasetTypeSnapshot | DatasetTypeBookmark,
+		MaxDepth: 1,
+		Props:    []Prop{DatasetPropCreateTXG, DatasetPropClones, DatasetPropUserrefs},
+		Less:     SortByProperty(DatasetPropCreateTXG, true),
+	}
+	err = IterDatasets(path, iopts, func(c *Dataset) (err error) {
+		ctxg, err := c.Properties[DatasetPropCreateTXG].Uint64()
+		if err != nil || ctxg <= txg {
+			return
+		}
+		name := c.Properties[DatasetPropName].Value
+		if c.IsBookmark() {
+			plan.Bookmarks = append(plan.Bookmarks, name)
+			return
+		}
+		plan.Snapshots = append(plan.Snapshots, name)
+		plan.Held = appendHeld(plan.Held, c)
+		plan.Clones, err = appendDependents(plan.Clones, &plan.Held, c)
+		return
+	})
+	return
+}
+
+// appendHeld appends snapshot with Userrefs property loaded to list if it
+// has user holds
+func appendHeld(list []string, snap *Dataset) []string {
+	if refs, err := snap.Properties[DatasetPropUserrefs].Uint64(); err == nil && refs > 0 {
+		list = append(list, snap.Properties[DatasetPropName].Value)
+	}
+	return list
+}
+
+// appendDependents appends clones of snapshot with Clones property loaded,
+// with all their descendants and clones of their snapshots, to list in
+// order they can be destroyed. Snapshots with user holds among them are
+// appended to held.
+func appendDependents(list []string, held *[]string, snap *Dataset) ([]string, error) {
+	clones := snap.Properties[DatasetPropClones].Value
+	if len(clones) == 0 {
+		return list, nil
+	}
+	type dependent struct {
+		name   string
+		clones []string
+	}
+	iopts := &DatasetIterOptions{Props: []Prop{DatasetPropClones, DatasetPropUserrefs}}
+	for _, clone := range strings.Split(clones, ",") {
+		// tree of clone, parents first
+		var tree []dependent
+		err := IterDatasets(clone, iopts, func(c *Dataset) (err error) {
+			dep := dependent{name: c.Properties[DatasetPropName].Value}
+			if c.IsSnapshot() {
+				*held = appendHeld(*held, c)
+				dep.clones, err = appendDependents(nil, held, c)
+			}
+			tree = append(tree, dep)
+			return
+		})
+		if err != nil {
+			return list, err
+		}
+		for i := len(tree) - 1; i >= 0; i-- {
+			list = append(list, tree[i].clones...)
+			list = append(list, tree[i].name)
+		}
+	}
+	return list, nil
+}
+
+// RollbackTo rolls dataset back to snapshot snap, which doesn't have to be
+// the most recent one, like zfs rollback. Fails if there are more recent
+// snapshots or bookmarks, unless options allow them, and their clones, to
+// be destroyed, if any of them is held, or if they differ from confirmed
+// plan. Returns everything destroyed, see RollbackPreview.
+func (d *Dataset) RollbackTo(snap *Dataset, opts RollbackOptions) (plan RollbackPlan, err error) {
+	if plan, err = d.RollbackPreview(snap); err != nil {
+		return
+	}
+	name := snap.Properties[DatasetPropName].Value
+	if opts.Confirmed != nil && !plan.equal(opts.Confirmed) {
+		err = fmt.Errorf("Failed to rollback to '%s': datasets to destroy changed since preview", name)
+		return
+	}
+	if len(plan.Held) > 0 {
+		err = fmt.Errorf("Failed to rollback to '%s': snapshots to destroy are held: %s",
+			name, strings.Join(plan.Held, ", "))
+		return
+	}
+	if len(plan.Snapshots)+len(plan.Bookmarks) > 0 && !opts.DestroyNewer && !opts.DestroyClones {
+		err = fmt.Errorf("Failed to rollback to '%s': more recent snapshots or bookmarks exist", name)
+		return
+	}
+	if len(plan.Clones) > 0 && !opts.DestroyClones {
+		err = fmt.Errorf("Failed to rollback to '%s': more recent snapshots have clones", name)
+		return
+	}
+	err = d.Rollback(snap, opts.Force)
+	return
+}
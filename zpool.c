@@ -87,6 +87,10 @@ char *sZPOOL_LOAD_DATA_THRESH = ZPOOL_LOAD_DATA_THRESH;
 char *sZPOOL_CONFIG_LOAD_TIME = ZPOOL_CONFIG_LOAD_TIME;
 char *sZPOOL_CONFIG_LOAD_DATA_ERRORS = ZPOOL_CONFIG_LOAD_DATA_ERRORS;
 char *sZPOOL_CONFIG_REWIND_TIME = ZPOOL_CONFIG_REWIND_TIME;
+char *sZPOOL_CONFIG_ALLOCATION_BIAS = ZPOOL_CONFIG_ALLOCATION_BIAS;
+char *sZPOOL_CONFIG_DRAID_NDATA = ZPOOL_CONFIG_DRAID_NDATA;
+char *sZPOOL_CONFIG_DRAID_NSPARES = ZPOOL_CONFIG_DRAID_NSPARES;
+char *sZPOOL_CONFIG_DRAID_NGROUPS = ZPOOL_CONFIG_DRAID_NGROUPS;
 
 static char _lasterr_[1024];
 
@@ -404,6 +408,52 @@ pool_scan_stat_ptr get_vdev_scan_stats(nvlist_t *nv) {
 	return vds;
 }
 
+pool_removal_stat_ptr get_vdev_removal_stats(nvlist_ptr nv) {
+	pool_removal_stat_ptr prs = NULL;
+	uint_t c;
+	int r = nvlist_lookup_uint64_array(nv, ZPOOL_CONFIG_REMOVAL_STATS, (uint64_t**)&prs, &c);
+	if(r != 0) {
+		return NULL;
+	}
+	return prs;
+}
+
+pool_checkpoint_stat_ptr get_vdev_checkpoint_stats(nvlist_ptr nv) {
+	pool_checkpoint_stat_ptr pcs = NULL;
+	uint_t c;
+	int r = nvlist_lookup_uint64_array(nv, ZPOOL_CONFIG_CHECKPOINT_STATS, (uint64_t**)&pcs, &c);
+	if(r != 0) {
+		return NULL;
+	}
+	return pcs;
+}
+
+/*
+ * Wait on its own libzfs handle, so it can block without holding shared
+ * libzfsHandle used concurrently by other calls. On libzfs error returns -1
+ * with error description copied to errbuf, otherwise errno.
+ */
+int do_zpool_wait(const char *name, zpool_wait_activity_t activity, boolean_t *missing, char *errbuf, size_t errlen) {
+	int ret = 0;
+	boolean_t waited = B_FALSE;
+	zpool_handle_t *zhp;
+	libzfs_handle_t *hdl = libzfs_init();
+	if (hdl == NULL) {
+		return (errno);
+	}
+	if ((zhp = zpool_open(hdl, name)) == NULL) {
+		ret = -1;
+	} else {
+		if (zpool_wait_status(zhp, activity, missing, &waited) != 0)
+			ret = -1;
+		zpool_close(zhp);
+	}
+	if (ret == -1)
+		strlcpy(errbuf, libzfs_error_description(hdl), errlen);
+	libzfs_fini(hdl);
+	return (ret);
+}
+
 vdev_children_ptr get_vdev_children(nvlist_t *nv) {
 	int r;
 	vdev_children_ptr children = malloc(sizeof(vdev_children_t));
@@ -440,24 +490,87 @@ vdev_children_ptr get_vdev_l2cache(nvlist_t *nv) {
 	return children;
 }
 
+/*
+ * Path of any device having one, leaf devices, whether present or not.
+ */
 const char *get_vdev_path(nvlist_ptr nv) {
 	char *path = NULL;
-	uint64_t notpresent = 0;
-	int r = nvlist_lookup_uint64(nv, ZPOOL_CONFIG_NOT_PRESENT, &notpresent);
-	if (r == 0 || notpresent != 0) {
-		if (  0 != nvlist_lookup_string(nv, ZPOOL_CONFIG_PATH, &path) ) {
-			return NULL;
-		}
+	if (  0 != nvlist_lookup_string(nv, ZPOOL_CONFIG_PATH, &path) ) {
+		return NULL;
 	}
 	return path;
 }
 
+/*
+ * Ashift set in vdev config, of the first device having it, 0 if not set.
+ */
+uint64_t get_config_ashift(nvlist_ptr nv) {
+	uint64_t ashift = 0;
+	nvlist_t **child;
+	uint_t children, c;
+	if (nvlist_lookup_uint64(nv, ZPOOL_CONFIG_ASHIFT, &ashift) == 0) {
+		return ashift;
+	}
+	if (nvlist_lookup_nvlist_array(nv, ZPOOL_CONFIG_CHILDREN, &child, &children) == 0) {
+		for (c = 0; c < children; c++) {
+			if ((ashift = get_config_ashift(child[c])) != 0) {
+				return ashift;
+			}
+		}
+	}
+	return 0;
+}
+
 uint64_t get_vdev_is_log(nvlist_ptr nv) {
 	uint64_t islog = B_FALSE;
 	nvlist_lookup_uint64(nv, ZPOOL_CONFIG_IS_LOG, &islog);
 	return islog;
 }
 
+const char *get_vdev_alloc_bias(nvlist_ptr nv) {
+	char *bias = NULL;
+	if (0 != nvlist_lookup_string(nv, ZPOOL_CONFIG_ALLOCATION_BIAS, &bias)) {
+		return NULL;
+	}
+	return bias;
+}
+
+uint64_t get_vdev_nparity(nvlist_ptr nv) {
+	uint64_t nparity = 0;
+	nvlist_lookup_uint64(nv, ZPOOL_CONFIG_NPARITY, &nparity);
+	return nparity;
+}
+
+uint64_t get_vdev_draid_ndata(nvlist_ptr nv) {
+	uint64_t ndata = 0;
+	nvlist_lookup_uint64(nv, ZPOOL_CONFIG_DRAID_NDATA, &ndata);
+	return ndata;
+}
+
+uint64_t get_vdev_draid_nspares(nvlist_ptr nv) {
+	uint64_t nspares = 0;
+	nvlist_lookup_uint64(nv, ZPOOL_CONFIG_DRAID_NSPARES, &nspares);
+	return nspares;
+}
+
+uint64_t get_vdev_draid_ngroups(nvlist_ptr nv) {
+	uint64_t ngroups = 0;
+	nvlist_lookup_uint64(nv, ZPOOL_CONFIG_DRAID_NGROUPS, &ngroups);
+	return ngroups;
+}
+
+int get_vdev_whole_disk(nvlist_ptr nv) {
+	uint64_t wholedisk = 0;
+	if (0 != nvlist_lookup_uint64(nv, ZPOOL_CONFIG_WHOLE_DISK, &wholedisk)) {
+		return -1;
+	}
+	return wholedisk != 0;
+}
+
+int is_whole_disk(const char *path) {
+	return zfs_dev_is_whole_disk(path) == B_TRUE;
+}
+
 
 // return
 uint64_t get_zpool_state(nvlist_ptr nv) {
@@ -488,6 +601,46 @@ const char *get_zpool_comment(nvlist_ptr nv) {
 	return comment;
 }
 
+uint64_t get_zpool_txg(nvlist_ptr nv) {
+	uint64_t txg = 0;
+	nvlist_lookup_uint64(nv, ZPOOL_CONFIG_POOL_TXG, &txg);
+	return txg;
+}
+
+uint64_t get_zpool_version(nvlist_ptr nv) {
+	uint64_t version = 0;
+	nvlist_lookup_uint64(nv, ZPOOL_CONFIG_VERSION, &version);
+	return version;
+}
+
+uint64_t get_zpool_hostid(nvlist_ptr nv) {
+	uint64_t hostid = 0;
+	nvlist_lookup_uint64(nv, ZPOOL_CONFIG_HOSTID, &hostid);
+	return hostid;
+}
+
+const char *get_zpool_hostname(nvlist_ptr nv) {
+	char *hostname = NULL;
+	if (0 != nvlist_lookup_string(nv, ZPOOL_CONFIG_HOSTNAME, &hostname)) {
+		return NULL;
+	}
+	return hostname;
+}
+
+uint64_t get_zpool_top_guid(nvlist_ptr nv) {
+	uint64_t guid = 0;
+	nvlist_lookup_uint64(nv, ZPOOL_CONFIG_TOP_GUID, &guid);
+	return guid;
+}
+
+nvlist_ptr unpack_vdev_label(char *buf, size_t size) {
+	nvlist_ptr config = NULL;
+	if (0 != nvlist_unpack(buf, size, &config, 0)) {
+		return NULL;
+	}
+	return config;
+}
+
 nvlist_ptr get_zpool_vdev_tree(nvlist_ptr nv) {
 	nvlist_ptr vdev_tree = NULL;
 	if ( 0 != nvlist_lookup_nvlist(nv, ZPOOL_CONFIG_VDEV_TREE,	&vdev_tree) ) {
@@ -518,19 +671,17 @@ nvlist_ptr go_zpool_search_import(libzfs_handle_ptr zfsh, int paths, char **path
 }
 
 
-int do_zpool_clear(zpool_list_t *pool, const char *device, u_int32_t load_policy) {
-	nvlist_t *policy = NULL;
-	int ret = 0;
-	if (nvlist_alloc(&policy, NV_UNIQUE_NAME, 0) != 0 ||
-	    nvlist_add_uint32(policy, ZPOOL_LOAD_POLICY, load_policy) != 0)
-		return (1);
-
-	if (zpool_clear(pool->zph, device, policy) != 0)
-		ret = 1;
-
-	nvlist_free(policy);
+int do_zpool_sync(zpool_list_t *pool) {
+	boolean_t force = B_FALSE;
+	return zpool_sync_one(pool->zph, &force);
+}
 
-	return (ret);
+char *resolve_vdev_path(const char *name) {
+	char path[MAXPATHLEN];
+	if (zfs_resolve_shortname(name, path, MAXPATHLEN) != 0) {
+		return NULL;
+	}
+	return strdup(path);
 }
 
 void collect_zpool_leaves(zpool_handle_t *zhp, nvlist_t *nvroot, nvlist_t *nv){
@@ -12,6 +12,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 	"unsafe"
 )
@@ -89,6 +90,10 @@ type VDevStat struct {
 	ScanRemoving   uint64           /* removing?	*/
 	ScanProcessed  uint64           /* scan processed bytes	*/
 	Fragmentation  uint64           /* device fragmentation */
+	InitializeDone uint64           /* bytes initialized */
+	InitializeEst  uint64           /* total bytes to initialize */
+	TrimDone       uint64           /* bytes trimmed */
+	TrimEst        uint64           /* total bytes to trim */
 }
 
 // PoolScanStat - Pool scan statistics
@@ -108,19 +113,33 @@ type PoolScanStat struct {
 	PassStart uint64 // Start time of scan pass
 }
 
+// resilvering returns true if resilver of pool is in progress
+func (s *PoolScanStat) resilvering() bool {
+	return s.Func == uint64(C.POOL_SCAN_RESILVER) && s.State == uint64(C.DSS_SCANNING)
+}
+
 // VDevTree ZFS virtual device tree
 type VDevTree struct {
-	Type     VDevType
-	Devices  []VDevTree // groups other devices (e.g. mirror)
-	Spares   []VDevTree
-	L2Cache  []VDevTree
-	Logs     *VDevTree
-	GUID     uint64
-	Parity   uint
-	Path     string
-	Name     string
-	Stat     VDevStat
-	ScanStat PoolScanStat
+	Type      VDevType
+	Devices   []VDevTree // groups other devices (e.g. mirror)
+	Spares    []VDevTree
+	L2Cache   []VDevTree
+	Logs      *VDevTree
+	GUID      uint64
+	Parity    uint
+	AllocBias VDevAllocBias // allocation class of top level device
+	// dRAID layout, DraidChildren defaults to number of Devices and
+	// DraidData to at most 8 data devices per redundancy group
+	DraidData     uint
+	DraidChildren uint
+	DraidSpares   uint
+	DraidGroups   uint          // calculated on create
+	Path          string        // path of leaf devices, present or missing
+	WholeDisk     VDevWholeDisk // auto detected on create if not set
+	Size          uint64        // device size in bytes, used only to validate layouts
+	Name          string
+	Stat          VDevStat
+	ScanStat      PoolScanStat
 }
 
 // ExportedPool is type representing ZFS pool available for import
@@ -165,6 +184,13 @@ func PoolOpen(name string) (pool Pool, err error) {
 }
 
 func poolGetConfig(name string, nv C.nvlist_ptr) (vdevs VDevTree, err error) {
+	return vdevGetConfig(name, nv, true)
+}
+
+// vdevGetConfig converts vdev config nvlist to VDevTree, set withStats to
+// false for configs that are not generated by pool (e.g. on create) and
+// don't include vdev statistics
+func vdevGetConfig(name string, nv C.nvlist_ptr, withStats bool) (vdevs VDevTree, err error) {
 	var dtype C.char_ptr
 	var vs C.vdev_stat_ptr
 	var ps C.pool_scan_stat_ptr
@@ -180,31 +206,47 @@ func poolGetConfig(name string, nv C.nvlist_ptr) (vdevs VDevTree, err error) {
 	}
 
 	vdevs.GUID = uint64(C.get_vdev_guid(nv))
+	vdevs.Parity = uint(C.get_vdev_nparity(nv))
+	if bias := C.get_vdev_alloc_bias(nv); bias != nil {
+		vdevs.AllocBias = VDevAllocBias(C.GoString(bias))
+	}
+	if vdevs.Type == VDevTypeDraid {
+		vdevs.DraidData = uint(C.get_vdev_draid_ndata(nv))
+		vdevs.DraidSpares = uint(C.get_vdev_draid_nspares(nv))
+		vdevs.DraidGroups = uint(C.get_vdev_draid_ngroups(nv))
+	}
 
 	// Fetch vdev state
 	if vs = C.get_vdev_stats(nv); vs == nil {
-		err = fmt.Errorf("Failed to fetch %s", C.ZPOOL_CONFIG_VDEV_STATS)
-		return
+		if withStats {
+			err = fmt.Errorf("Failed to fetch %s", C.ZPOOL_CONFIG_VDEV_STATS)
+			return
+		}
+	} else {
+		vdevs.Stat.Timestamp = time.Duration(vs.vs_timestamp)
+		vdevs.Stat.State = VDevState(vs.vs_state)
+		vdevs.Stat.Aux = VDevAux(vs.vs_aux)
+		vdevs.Stat.Alloc = uint64(vs.vs_alloc)
+		vdevs.Stat.Space = uint64(vs.vs_space)
+		vdevs.Stat.DSpace = uint64(vs.vs_dspace)
+		vdevs.Stat.RSize = uint64(vs.vs_rsize)
+		vdevs.Stat.ESize = uint64(vs.vs_esize)
+		for z := 0; z < ZIOTypes; z++ {
+			vdevs.Stat.Ops[z] = uint64(vs.vs_ops[z])
+			vdevs.Stat.Bytes[z] = uint64(vs.vs_bytes[z])
+		}
+		vdevs.Stat.ReadErrors = uint64(vs.vs_read_errors)
+		vdevs.Stat.WriteErrors = uint64(vs.vs_write_errors)
+		vdevs.Stat.ChecksumErrors = uint64(vs.vs_checksum_errors)
+		vdevs.Stat.SelfHealed = uint64(vs.vs_self_healed)
+		vdevs.Stat.ScanRemoving = uint64(vs.vs_scan_removing)
+		vdevs.Stat.ScanProcessed = uint64(vs.vs_scan_processed)
+		vdevs.Stat.Fragmentation = uint64(vs.vs_fragmentation)
+		vdevs.Stat.InitializeDone = uint64(vs.vs_initialize_bytes_done)
+		vdevs.Stat.InitializeEst = uint64(vs.vs_initialize_bytes_est)
+		vdevs.Stat.TrimDone = uint64(vs.vs_trim_bytes_done)
+		vdevs.Stat.TrimEst = uint64(vs.vs_trim_bytes_est)
 	}
-	vdevs.Stat.Timestamp = time.Duration(vs.vs_timestamp)
-	vdevs.Stat.State = VDevState(vs.vs_state)
-	vdevs.Stat.Aux = VDevAux(vs.vs_aux)
-	vdevs.Stat.Alloc = uint64(vs.vs_alloc)
-	vdevs.Stat.Space = uint64(vs.vs_space)
-	vdevs.Stat.DSpace = uint64(vs.vs_dspace)
-	vdevs.Stat.RSize = uint64(vs.vs_rsize)
-	vdevs.Stat.ESize = uint64(vs.vs_esize)
-	for z := 0; z < ZIOTypes; z++ {
-		vdevs.Stat.Ops[z] = uint64(vs.vs_ops[z])
-		vdevs.Stat.Bytes[z] = uint64(vs.vs_bytes[z])
-	}
-	vdevs.Stat.ReadErrors = uint64(vs.vs_read_errors)
-	vdevs.Stat.WriteErrors = uint64(vs.vs_write_errors)
-	vdevs.Stat.ChecksumErrors = uint64(vs.vs_checksum_errors)
-	vdevs.Stat.SelfHealed = uint64(vs.vs_self_healed)
-	vdevs.Stat.ScanRemoving = uint64(vs.vs_scan_removing)
-	vdevs.Stat.ScanProcessed = uint64(vs.vs_scan_processed)
-	vdevs.Stat.Fragmentation = uint64(vs.vs_fragmentation)
 
 	// Fetch vdev scan stats
 	if ps = C.get_vdev_scan_stats(nv); ps != nil {
@@ -227,11 +269,20 @@ func poolGetConfig(name string, nv C.nvlist_ptr) (vdevs VDevTree, err error) {
 		// this object that reference childrens and count should be deallocated from memory
 		defer C.free(unsafe.Pointer(children))
 		vdevs.Devices = make([]VDevTree, 0, children.count)
+		if vdevs.Type == VDevTypeDraid {
+			vdevs.DraidChildren = uint(children.count)
+		}
 	}
 	path := C.get_vdev_path(nv)
 	if path != nil {
 		vdevs.Path = C.GoString(path)
 	}
+	switch C.get_vdev_whole_disk(nv) {
+	case 1:
+		vdevs.WholeDisk = VDevWholeDiskOn
+	case 0:
+		vdevs.WholeDisk = VDevWholeDiskOff
+	}
 	for c := C.uint_t(0); children != nil && c < children.count; c++ {
 		var islog = C.uint64_t(C.B_FALSE)
 
@@ -240,8 +291,8 @@ func poolGetConfig(name string, nv C.nvlist_ptr) (vdevs VDevTree, err error) {
 		vname := C.zpool_vdev_name(C.libzfsHandle, nil, C.nvlist_array_at(children.first, c),
 			C.B_TRUE)
 		var vdev VDevTree
-		vdev, err = poolGetConfig(C.GoString(vname),
-			C.nvlist_array_at(children.first, c))
+		vdev, err = vdevGetConfig(C.GoString(vname),
+			C.nvlist_array_at(children.first, c), withStats)
 		C.free(unsafe.Pointer(vname))
 		if err != nil {
 			return
@@ -255,7 +306,7 @@ func poolGetConfig(name string, nv C.nvlist_ptr) (vdevs VDevTree, err error) {
 	return
 }
 
-func poolGetSpares(name string, nv C.nvlist_ptr) (vdevs []VDevTree, err error) {
+func poolGetSpares(name string, nv C.nvlist_ptr, withStats bool) (vdevs []VDevTree, err error) {
 	// Fetch the spares
 	var spares C.vdev_children_ptr
 	spares = C.get_vdev_spares(nv)
@@ -268,8 +319,8 @@ func poolGetSpares(name string, nv C.nvlist_ptr) (vdevs []VDevTree, err error) {
 		vname := C.zpool_vdev_name(C.libzfsHandle, nil, C.nvlist_array_at(spares.first, c),
 			C.B_TRUE)
 		var vdev VDevTree
-		vdev, err = poolGetConfig(C.GoString(vname),
-			C.nvlist_array_at(spares.first, c))
+		vdev, err = vdevGetConfig(C.GoString(vname),
+			C.nvlist_array_at(spares.first, c), withStats)
 		C.free(unsafe.Pointer(vname))
 		if err != nil {
 			return
@@ -279,7 +330,7 @@ func poolGetSpares(name string, nv C.nvlist_ptr) (vdevs []VDevTree, err error) {
 	return
 }
 
-func poolGetL2Cache(name string, nv C.nvlist_ptr) (vdevs []VDevTree, err error) {
+func poolGetL2Cache(name string, nv C.nvlist_ptr, withStats bool) (vdevs []VDevTree, err error) {
 	// Fetch the spares
 	var l2cache C.vdev_children_ptr
 	l2cache = C.get_vdev_l2cache(nv)
@@ -292,8 +343,8 @@ func poolGetL2Cache(name string, nv C.nvlist_ptr) (vdevs []VDevTree, err error)
 		vname := C.zpool_vdev_name(C.libzfsHandle, nil, C.nvlist_array_at(l2cache.first, c),
 			C.B_TRUE)
 		var vdev VDevTree
-		vdev, err = poolGetConfig(C.GoString(vname),
-			C.nvlist_array_at(l2cache.first, c))
+		vdev, err = vdevGetConfig(C.GoString(vname),
+			C.nvlist_array_at(l2cache.first, c), withStats)
 		C.free(unsafe.Pointer(vname))
 		if err != nil {
 			return
@@ -667,6 +718,16 @@ func (vdev *VDevTree) isGrouping() (grouping bool, mindevs, maxdevs int) {
 		}
 		mindevs = int(vdev.Parity) + 1
 		maxdevs = 255
+	} else if vdev.Type == VDevTypeDraid {
+		grouping = true
+		if vdev.Parity == 0 {
+			vdev.Parity = 1
+		}
+		if vdev.Parity > 3 {
+			vdev.Parity = 3
+		}
+		mindevs = int(vdev.Parity + vdev.DraidSpares + 1)
+		maxdevs = 255
 	} else if vdev.Type == VDevTypeMirror {
 		grouping = true
 		mindevs = 2
@@ -679,12 +740,65 @@ func (vdev *VDevTree) isGrouping() (grouping bool, mindevs, maxdevs int) {
 
 func (vdev *VDevTree) isLog() (r C.uint64_t) {
 	r = 0
-	if vdev.Type == VDevTypeLog {
+	if vdev.Type == VDevTypeLog || vdev.AllocBias == VDevAllocBiasLog {
 		r = 1
 	}
 	return
 }
 
+// draidLayout validates dRAID specification and calculates number of data
+// devices and redundancy groups the same way zpool command does
+func (vdev *VDevTree) draidLayout() (ndata, ngroups uint, err error) {
+	children := uint(len(vdev.Devices))
+	if vdev.DraidChildren > 0 && vdev.DraidChildren != children {
+		err = fmt.Errorf(
+			"Invalid vdev specification: %s requires %d children, but %d are given",
+			vdev.Type, vdev.DraidChildren, children)
+		return
+	}
+	parity := vdev.Parity
+	if parity == 0 {
+		parity = 1
+	}
+	if children <= parity+vdev.DraidSpares {
+		err = fmt.Errorf(
+			"Invalid vdev specification: %s has not enough children for %d parity and %d spares",
+			vdev.Type, parity, vdev.DraidSpares)
+		return
+	}
+	if ndata = vdev.DraidData; ndata == 0 {
+		ndata = children - vdev.DraidSpares - parity
+		if ndata > 8 {
+			ndata = 8
+		}
+	}
+	if ndata+parity > children-vdev.DraidSpares {
+		err = fmt.Errorf(
+			"Invalid vdev specification: %s requested %d data and %d parity devices, but only %d children are available",
+			vdev.Type, ndata, parity, children-vdev.DraidSpares)
+		return
+	}
+	// minimum number of groups required to fill a slice
+	ngroups = 1
+	for (ngroups*(ndata+parity))%(children-vdev.DraidSpares) != 0 {
+		ngroups++
+	}
+	return
+}
+
+func (vdev *VDevTree) addAllocBias(nv *C.struct_nvlist) (err error) {
+	if vdev.AllocBias == VDevAllocBiasNone {
+		return
+	}
+	csBias := C.CString(string(vdev.AllocBias))
+	r := C.nvlist_add_string(nv, C.sZPOOL_CONFIG_ALLOCATION_BIAS, csBias)
+	C.free(unsafe.Pointer(csBias))
+	if r != 0 {
+		err = errors.New("Failed to set vdev allocation bias")
+	}
+	return
+}
+
 func toCPoolProperties(props PoolProperties) (cprops C.nvlist_ptr) {
 	cprops = C.new_property_nvlist()
 	for prop, value := range props {
@@ -721,7 +835,47 @@ func toCDatasetProperties(props DatasetProperties) (cprops C.nvlist_ptr) {
 	return
 }
 
+// wholeDisk returns whole disk flag of leaf device, files are never whole
+// disks and unless set explicitly it is detected for disks from device label
+func (vdev *VDevTree) wholeDisk() C.uint64_t {
+	switch {
+	case vdev.WholeDisk == VDevWholeDiskOn:
+		return 1
+	case vdev.WholeDisk == VDevWholeDiskOff || vdev.Type != VDevTypeDisk:
+		return 0
+	case len(vdev.Path) == 0:
+		return 1
+	}
+	csPath := C.CString(vdev.Path)
+	defer C.free(unsafe.Pointer(csPath))
+	if C.is_whole_disk(csPath) != 0 {
+		return 1
+	}
+	return 0
+}
+
+// resolvePath resolves short disk names (sda) to full path (/dev/sda)
+func (vdev *VDevTree) resolvePath() (err error) {
+	if vdev.Type != VDevTypeDisk || len(vdev.Path) == 0 ||
+		strings.HasPrefix(vdev.Path, "/") {
+		return
+	}
+	csName := C.CString(vdev.Path)
+	defer C.free(unsafe.Pointer(csName))
+	csPath := C.resolve_vdev_path(csName)
+	if csPath == nil {
+		err = fmt.Errorf("Failed to resolve vdev path %s", vdev.Path)
+		return
+	}
+	vdev.Path = C.GoString(csPath)
+	C.free(unsafe.Pointer(csPath))
+	return
+}
+
 func buildVdev(vdev VDevTree, ashift int) (nvvdev *C.struct_nvlist, err error) {
+	if err = vdev.resolvePath(); err != nil {
+		return
+	}
 	if r := C.nvlist_alloc(&nvvdev, C.NV_UNIQUE_NAME, 0); r != 0 {
 		err = errors.New("Failed to allocate vdev")
 		return
@@ -739,8 +893,11 @@ func buildVdev(vdev VDevTree, ashift int) (nvvdev *C.struct_nvlist, err error) {
 		err = errors.New("Failed to allocate vdev (is_log)")
 		return
 	}
+	if err = vdev.addAllocBias(nvvdev); err != nil {
+		return
+	}
 	if r := C.nvlist_add_uint64(nvvdev,
-		C.sZPOOL_CONFIG_WHOLE_DISK, 1); r != 0 {
+		C.sZPOOL_CONFIG_WHOLE_DISK, vdev.wholeDisk()); r != 0 {
 		err = errors.New("Failed to allocate vdev nvvdev (whdisk)")
 		return
 	}
@@ -810,6 +967,46 @@ func buildVDevTree(root *C.nvlist_t, rtype VDevType, vdevs, spares, l2cache []VD
 					return
 				}
 			}
+			if vdev.Type == VDevTypeDraid {
+				var ndata, ngroups uint
+				if ndata, ngroups, err = vdev.draidLayout(); err != nil {
+					return
+				}
+				if r := C.nvlist_add_uint64(child,
+					C.sZPOOL_CONFIG_NPARITY,
+					C.uint64_t(vdev.Parity)); r != 0 {
+					err = errors.New("Failed to allocate vdev (parity)")
+					return
+				}
+				if r := C.nvlist_add_uint64(child,
+					C.sZPOOL_CONFIG_DRAID_NDATA,
+					C.uint64_t(ndata)); r != 0 {
+					err = errors.New("Failed to allocate vdev (draid ndata)")
+					return
+				}
+				if r := C.nvlist_add_uint64(child,
+					C.sZPOOL_CONFIG_DRAID_NSPARES,
+					C.uint64_t(vdev.DraidSpares)); r != 0 {
+					err = errors.New("Failed to allocate vdev (draid nspares)")
+					return
+				}
+				if r := C.nvlist_add_uint64(child,
+					C.sZPOOL_CONFIG_DRAID_NGROUPS,
+					C.uint64_t(ngroups)); r != 0 {
+					err = errors.New("Failed to allocate vdev (draid ngroups)")
+					return
+				}
+			}
+			if vdev.AllocBias != VDevAllocBiasNone {
+				if r := C.nvlist_add_uint64(child, C.sZPOOL_CONFIG_IS_LOG,
+					vdev.isLog()); r != 0 {
+					err = errors.New("Failed to allocate vdev (is_log)")
+					return
+				}
+				if err = vdev.addAllocBias(child); err != nil {
+					return
+				}
+			}
 			if err = buildVDevTree(child, vdev.Type, vdev.Devices, nil, nil,
 				props); err != nil {
 				return
@@ -889,21 +1086,25 @@ func buildVdevL2Cache(root *C.nvlist_t, rtype VDevType, vdevs []VDevTree, ashift
 		C.nvlist_array_set(l2cache, C.int(i), child)
 	}
 	if r := C.nvlist_add_nvlist_array(root,
-		C.sZPOOL_CONFIG_SPARES, l2cache, C.uint_t(len(vdevs))); r != 0 {
+		C.sZPOOL_CONFIG_L2CACHE, l2cache, C.uint_t(len(vdevs))); r != 0 {
 		err = errors.New("Failed to allocate vdev l2cache")
 	}
 	return
 }
 
-// PoolCreate create ZFS pool per specs, features and properties of pool and root dataset
-func PoolCreate(name string, vdev VDevTree, features map[string]string,
-	props PoolProperties, fsprops DatasetProperties) (pool Pool, err error) {
-	// create root vdev nvroot
-	var nvroot *C.struct_nvlist
+// buildPoolRoot builds root vdev nvlist of pool with given vdev tree
+func buildPoolRoot(vdev VDevTree, props PoolProperties) (nvroot *C.struct_nvlist,
+	devices []VDevTree, err error) {
 	if r := C.nvlist_alloc(&nvroot, C.NV_UNIQUE_NAME, 0); r != 0 {
 		err = errors.New("Failed to allocate root vdev")
 		return
 	}
+	defer func() {
+		if err != nil {
+			C.nvlist_free(nvroot)
+			nvroot = nil
+		}
+	}()
 	csTypeRoot := C.CString(string(VDevTypeRoot))
 	r := C.nvlist_add_string(nvroot, C.sZPOOL_CONFIG_TYPE,
 		csTypeRoot)
@@ -912,11 +1113,31 @@ func PoolCreate(name string, vdev VDevTree, features map[string]string,
 		err = errors.New("Failed to allocate root vdev")
 		return
 	}
-	defer C.nvlist_free(nvroot)
 
 	// Now we need to build specs (vdev hierarchy)
-	if err = buildVDevTree(nvroot, VDevTypeRoot, vdev.Devices, vdev.Spares, vdev.L2Cache, props); err != nil {
-		return
+	devices = vdev.Devices
+	if vdev.Logs != nil {
+		logs := *vdev.Logs
+		if logs.AllocBias == VDevAllocBiasNone {
+			logs.AllocBias = VDevAllocBiasLog
+		}
+		devices = append(append(make([]VDevTree, 0, len(devices)+1), devices...), logs)
+	}
+	err = buildVDevTree(nvroot, VDevTypeRoot, devices, vdev.Spares, vdev.L2Cache, props)
+	return
+}
+
+// enableDefaultFeatures adds to features ones required by devices and ones
+// enabled per default on pool creation
+func enableDefaultFeatures(features map[string]string, devices []VDevTree) {
+	// Enable features required by devices allocation classes and dRAID
+	for _, d := range devices {
+		if d.AllocBias == VDevAllocBiasSpecial || d.AllocBias == VDevAllocBiasDedup {
+			features["allocation_classes"] = FENABLED
+		}
+		if d.Type == VDevTypeDraid {
+			features["draid"] = FENABLED
+		}
 	}
 
 	// Enable 0.6.5 features per default
@@ -936,6 +1157,64 @@ func PoolCreate(name string, vdev VDevTree, features map[string]string,
 	features["skein"] = FENABLED
 	features["edonr"] = FENABLED
 	features["userobj_accounting"] = FENABLED
+}
+
+// PoolCreatePlan describes pool as it would be created by PoolCreate
+type PoolCreatePlan struct {
+	Name  string
+	VDevs VDevTree
+	// Ashift of devices in generated config, 0 if ZFS detects it from
+	// sector size of devices on creation
+	Ashift   int
+	Features map[string]string
+}
+
+// PoolCreateDryRun builds pool vdev hierarchy the same way PoolCreate does,
+// with device paths resolved, and returns resulting layout, ashift and
+// feature set without creating the pool (zpool create -n).
+func PoolCreateDryRun(name string, vdev VDevTree, features map[string]string,
+	props PoolProperties) (plan PoolCreatePlan, err error) {
+	csName := C.CString(name)
+	defer C.free(unsafe.Pointer(csName))
+	if C.zpool_name_valid(C.libzfsHandle, C.B_FALSE, csName) == C.B_FALSE {
+		err = fmt.Errorf("Invalid pool name %s", name)
+		return
+	}
+	nvroot, devices, err := buildPoolRoot(vdev, props)
+	if err != nil {
+		return
+	}
+	defer C.nvlist_free(nvroot)
+
+	plan.Name = name
+	plan.Features = make(map[string]string, len(features))
+	for fname, fval := range features {
+		plan.Features[fname] = fval
+	}
+	enableDefaultFeatures(plan.Features, devices)
+	plan.Ashift = int(C.get_config_ashift(nvroot))
+
+	if plan.VDevs, err = vdevGetConfig(name, nvroot, false); err != nil {
+		return
+	}
+	if plan.VDevs.Spares, err = poolGetSpares(name, nvroot, false); err != nil {
+		return
+	}
+	plan.VDevs.L2Cache, err = poolGetL2Cache(name, nvroot, false)
+	return
+}
+
+// PoolCreate create ZFS pool per specs, features and properties of pool and root dataset
+func PoolCreate(name string, vdev VDevTree, features map[string]string,
+	props PoolProperties, fsprops DatasetProperties) (pool Pool, err error) {
+	// create root vdev nvroot
+	nvroot, devices, err := buildPoolRoot(vdev, props)
+	if err != nil {
+		return
+	}
+	defer C.nvlist_free(nvroot)
+
+	enableDefaultFeatures(features, devices)
 
 	// convert properties
 	cprops := toCPoolProperties(props)
@@ -1044,6 +1323,34 @@ func (pool *Pool) ExportForce(log string) (err error) {
 	return
 }
 
+// Reguid generates new unique GUID for the pool, e.g. to import block level
+// copy of the pool on the same system as original
+func (pool *Pool) Reguid() (err error) {
+	if pool.list == nil {
+		err = errors.New(msgPoolIsNil)
+		return
+	}
+	if rc := C.zpool_reguid(pool.list.zph); rc != 0 {
+		err = LastError()
+		return
+	}
+	err = pool.ReloadProperties()
+	return
+}
+
+// Sync forces all in-core dirty data of the pool to be written to the
+// primary pool storage.
+func (pool *Pool) Sync() (err error) {
+	if pool.list == nil {
+		err = errors.New(msgPoolIsNil)
+		return
+	}
+	if rc := C.do_zpool_sync(pool.list); rc != 0 {
+		err = LastError()
+	}
+	return
+}
+
 // VDevTree - Fetch pool's current vdev tree configuration, state and stats
 func (pool *Pool) VDevTree() (vdevs VDevTree, err error) {
 	var nvroot *C.struct_nvlist
@@ -1063,8 +1370,8 @@ func (pool *Pool) VDevTree() (vdevs VDevTree, err error) {
 	if vdevs, err = poolGetConfig(poolName, nvroot); err != nil {
 		return
 	}
-	vdevs.Spares, err = poolGetSpares(poolName, nvroot)
-	vdevs.L2Cache, err = poolGetL2Cache(poolName, nvroot)
+	vdevs.Spares, err = poolGetSpares(poolName, nvroot, true)
+	vdevs.L2Cache, err = poolGetL2Cache(poolName, nvroot, true)
 	return
 }
 
@@ -30,6 +30,8 @@ typedef struct vdev_children vdev_children_t;
 typedef struct vdev_children* vdev_children_ptr;
 
 typedef struct pool_scan_stat* pool_scan_stat_ptr;
+typedef struct pool_removal_stat* pool_removal_stat_ptr;
+typedef struct pool_checkpoint_stat* pool_checkpoint_stat_ptr;
 
 zpool_list_t *create_zpool_list_item();
 void zprop_source_tostr(char *dst, zprop_source_t source);
@@ -65,24 +67,52 @@ const char *get_vdev_type(nvlist_ptr nv);
 uint64_t get_vdev_guid(nvlist_ptr nv);
 const vdev_stat_ptr get_vdev_stats(nvlist_ptr nv);
 pool_scan_stat_ptr get_vdev_scan_stats(nvlist_t *nv);
+pool_removal_stat_ptr get_vdev_removal_stats(nvlist_ptr nv);
+pool_checkpoint_stat_ptr get_vdev_checkpoint_stats(nvlist_ptr nv);
+int do_zpool_wait(const char *name, zpool_wait_activity_t activity, boolean_t *missing, char *errbuf, size_t errlen);
 vdev_children_ptr get_vdev_children(nvlist_t *nv);
 vdev_children_ptr get_vdev_spares(nvlist_t *nv);
 vdev_children_ptr get_vdev_l2cache(nvlist_t *nv);
 const char *get_vdev_path(nvlist_ptr nv);
+uint64_t get_config_ashift(nvlist_ptr nv);
 uint64_t get_vdev_is_log(nvlist_ptr nv);
+const char *get_vdev_alloc_bias(nvlist_ptr nv);
+uint64_t get_vdev_nparity(nvlist_ptr nv);
+uint64_t get_vdev_draid_ndata(nvlist_ptr nv);
+uint64_t get_vdev_draid_nspares(nvlist_ptr nv);
+uint64_t get_vdev_draid_ngroups(nvlist_ptr nv);
+int get_vdev_whole_disk(nvlist_ptr nv);
+int is_whole_disk(const char *path);
 
 uint64_t get_zpool_state(nvlist_ptr nv);
 uint64_t get_zpool_guid(nvlist_ptr nv);
 const char *get_zpool_name(nvlist_ptr nv);
 const char *get_zpool_comment(nvlist_ptr nv);
 
+uint64_t get_zpool_txg(nvlist_ptr nv);
+uint64_t get_zpool_version(nvlist_ptr nv);
+uint64_t get_zpool_hostid(nvlist_ptr nv);
+const char *get_zpool_hostname(nvlist_ptr nv);
+uint64_t get_zpool_top_guid(nvlist_ptr nv);
+nvlist_ptr unpack_vdev_label(char *buf, size_t size);
 nvlist_ptr get_zpool_vdev_tree(nvlist_ptr nv);
 
 nvlist_ptr go_zpool_search_import(libzfs_handle_ptr zfsh, int paths, char **path, boolean_t do_scan);
 
 uint64_t set_zpool_vdev_online(zpool_list_t *pool, const char *path, int flags);
 int set_zpool_vdev_offline(zpool_list_t *pool, const char *path, boolean_t istmp, boolean_t force);
-int do_zpool_clear(zpool_list_t *pool, const char *device, u_int32_t rewind_policy);
+int set_zpool_vdev_fault(zpool_list_t *pool, uint64_t guid, uint64_t aux);
+int set_zpool_vdev_degrade(zpool_list_t *pool, uint64_t guid, uint64_t aux);
+int do_zpool_vdev_attach(zpool_list_t *pool, const char *old_disk, const char *new_disk, nvlist_ptr nvroot, int replacing);
+int do_zpool_vdev_detach(zpool_list_t *pool, const char *path);
+int do_zpool_clear(zpool_list_t *pool, const char *device, uint32_t rewind_policy);
+int do_zpool_clear_rewind(zpool_list_t *pool, const char *device, uint32_t rewind_policy, nvlist_ptr *loadinfo);
+int64_t get_rewind_time(nvlist_ptr loadinfo);
+uint64_t get_load_time(nvlist_ptr loadinfo);
+uint64_t get_load_data_errors(nvlist_ptr loadinfo);
+int do_zpool_sync(zpool_list_t *pool);
+char *resolve_vdev_path(const char *name);
+int do_zpool_labelclear(const char *device, boolean_t force);
 void collect_zpool_leaves(zpool_handle_t *zhp, nvlist_t *nvroot, nvlist_t *nv);
 
 
@@ -159,6 +189,10 @@ extern char *sZPOOL_LOAD_DATA_THRESH;
 extern char *sZPOOL_CONFIG_LOAD_TIME;
 extern char *sZPOOL_CONFIG_LOAD_DATA_ERRORS;
 extern char *sZPOOL_CONFIG_REWIND_TIME;
+extern char *sZPOOL_CONFIG_ALLOCATION_BIAS;
+extern char *sZPOOL_CONFIG_DRAID_NDATA;
+extern char *sZPOOL_CONFIG_DRAID_NSPARES;
+extern char *sZPOOL_CONFIG_DRAID_NGROUPS;
 
 
 #endif
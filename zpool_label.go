@@ -0,0 +1,179 @@
+package zfs
+
+// #include <stdlib.h>
+// #include <libzfs.h>
+// #include "common.h"
+// #include "zpool.h"
+// #include "zfs.h"
+import "C"
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"unsafe"
+)
+
+// On-disk label layout, see vdev_label_t in sys/vdev_impl.h
+const (
+	vdevLabels      = 4
+	vdevLabelSize   = 256 << 10
+	vdevPhysOffset  = 16 << 10 // padding and boot environment block
+	vdevPhysSize    = 112 << 10
+	zioEckSize      = 40 // embedded checksum, magic and SHA-256 checksum
+	zioEckMagic     = 0x0210da7ab10c7a11
+	zioEckMagicSwap = 0x117a0cb17ada1002
+)
+
+// VDevLabel is decoded on-disk label of pool device. Every device has four
+// copies of label, two at the beginning and two at the end of the device.
+type VDevLabel struct {
+	Index    int   // label number 0-3
+	Offset   int64 // label offset on the device
+	Err      error // nil if label is valid
+	PoolName string
+	PoolGUID uint64
+	TXG      uint64
+	Version  uint64
+	HostID   uint64
+	Hostname string
+	State    PoolState
+	GUID     uint64   // device GUID
+	TopGUID  uint64   // GUID of top level device the device belongs to
+	VDevs    VDevTree // top level device the device belongs to
+}
+
+// VDevLabels labels of a device as returned by ReadLabel
+type VDevLabels []VDevLabel
+
+// Valid returns number of valid labels
+func (labels VDevLabels) Valid() (valid int) {
+	for _, l := range labels {
+		if l.Err == nil {
+			valid++
+		}
+	}
+	return
+}
+
+// Latest returns valid label with highest transaction group, the one used
+// on pool import, or error if there are no valid labels.
+func (labels VDevLabels) Latest() (label VDevLabel, err error) {
+	found := false
+	for _, l := range labels {
+		if l.Err == nil && (!found || l.TXG > label.TXG) {
+			label = l
+			found = true
+		}
+	}
+	if !found {
+		err = errors.New("No valid ZFS label found")
+	}
+	return
+}
+
+// ReadLabel reads and decodes all four ZFS labels directly from the device
+// or file, without importing or scanning for pools. Labels that are missing
+// or corrupted have Err set. Returns error only if device can't be read.
+func ReadLabel(devicePath string) (labels VDevLabels, err error) {
+	f, err := os.Open(devicePath)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return
+	}
+	size &^= vdevLabelSize - 1
+	if size < vdevLabels*vdevLabelSize {
+		err = fmt.Errorf("Device '%s' is too small to hold ZFS labels", devicePath)
+		return
+	}
+	buf := make([]byte, vdevPhysSize)
+	labels = make(VDevLabels, vdevLabels)
+	for l := range labels {
+		label := &labels[l]
+		label.Index = l
+		label.Offset = int64(l) * vdevLabelSize
+		if l >= vdevLabels/2 {
+			label.Offset += size - vdevLabels*vdevLabelSize
+		}
+		phys := label.Offset + vdevPhysOffset
+		if _, err = f.ReadAt(buf, phys); err != nil {
+			return
+		}
+		if label.Err = verifyLabelChecksum(buf, uint64(phys)); label.Err == nil {
+			label.Err = label.decode(buf[:vdevPhysSize-zioEckSize])
+		}
+	}
+	return
+}
+
+// verifyLabelChecksum verifies embedded SHA-256 checksum of vdev_phys block
+// read from device offset, checksum verifier of labels is their offset
+func verifyLabelChecksum(buf []byte, offset uint64) (err error) {
+	var order binary.ByteOrder = binary.LittleEndian
+	eck := buf[len(buf)-zioEckSize:]
+	switch order.Uint64(eck) {
+	case zioEckMagic:
+	case zioEckMagicSwap:
+		order = binary.BigEndian
+	case 0:
+		return errors.New("No ZFS label")
+	default:
+		return errors.New("Invalid label magic")
+	}
+	var cksum [4]uint64
+	for i := range cksum {
+		cksum[i] = order.Uint64(eck[8+8*i:])
+	}
+	verifier := make([]byte, len(buf))
+	copy(verifier, buf)
+	order.PutUint64(verifier[len(buf)-32:], offset)
+	for i := 1; i < 4; i++ {
+		order.PutUint64(verifier[len(buf)-32+8*i:], 0)
+	}
+	digest := sha256.Sum256(verifier)
+	for i := range cksum {
+		if cksum[i] != binary.BigEndian.Uint64(digest[8*i:]) {
+			return errors.New("Invalid label checksum")
+		}
+	}
+	return
+}
+
+// decode unpacks label config nvlist
+func (label *VDevLabel) decode(packed []byte) (err error) {
+	cbuf := C.CBytes(packed)
+	defer C.free(cbuf)
+	config := C.unpack_vdev_label((*C.char)(cbuf), C.size_t(len(packed)))
+	if config == nil {
+		return errors.New("Failed to unpack label config")
+	}
+	defer C.nvlist_free(config)
+
+	if name := C.get_zpool_name(config); name != nil {
+		label.PoolName = C.GoString(name)
+	}
+	if hostname := C.get_zpool_hostname(config); hostname != nil {
+		label.Hostname = C.GoString(hostname)
+	}
+	label.PoolGUID = uint64(C.get_zpool_guid(config))
+	label.TXG = uint64(C.get_zpool_txg(config))
+	label.Version = uint64(C.get_zpool_version(config))
+	label.HostID = uint64(C.get_zpool_hostid(config))
+	label.State = PoolState(C.get_zpool_state(config))
+	label.GUID = uint64(C.get_vdev_guid(config))
+	label.TopGUID = uint64(C.get_zpool_top_guid(config))
+
+	// labels of hot spares and cache devices don't have vdev tree
+	if nvroot := C.get_zpool_vdev_tree(config); nvroot != nil {
+		vname := C.zpool_vdev_name(C.libzfsHandle, nil, nvroot, C.B_TRUE)
+		label.VDevs, err = vdevGetConfig(C.GoString(vname), nvroot, false)
+		C.free(unsafe.Pointer(vname))
+	}
+	return
+}
@@ -0,0 +1,154 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SpareEvent reports hot spare activated for failed device, or returned to
+// the spares list, by CheckSpares. Errors of pool checks of WatchSpares are
+// reported as events with only Err set.
+type SpareEvent struct {
+	Failed    string // path of failed device
+	Spare     string // path of hot spare, the last one tried if all failed
+	Activated bool   // spare activated
+	Returned  bool   // spare returned to spares list
+	Err       error  // error activating or returning spare
+}
+
+// SpareCheckOptions options of CheckSpares and WatchSpares
+type SpareCheckOptions struct {
+	// ReturnSpares returns spares in use to spares list once device they
+	// replace is healthy again and resilver is finished
+	ReturnSpares bool
+}
+
+// isFailed returns true if leaf device should be replaced by hot spare
+func (vdev *VDevTree) isFailed() bool {
+	switch vdev.Stat.State {
+	case VDevStateRemoved, VDevStateCantOpen, VDevStateFaulted:
+		return true
+	}
+	return false
+}
+
+// CheckSpares activates available hot spare for every faulted, removed or
+// unavailable data device of the pool that is not already spared, the way
+// ZFS event daemon (zed) does. Log devices are never spared. Returns all
+// spare activations and returns attempted.
+func (pool *Pool) CheckSpares(opts SpareCheckOptions) (events []SpareEvent, err error) {
+	if err = pool.RefreshStats(); err != nil {
+		return
+	}
+	vdevs, err := pool.VDevTree()
+	if err != nil {
+		return
+	}
+	// spares must not be detached while they are being resilvered, or
+	// used to resilver the device they replace
+	resilvering := vdevs.ScanStat.resilvering()
+
+	var failed []*VDevTree
+	inuse := make(map[string]bool)
+	var data VDevTree
+	for _, d := range vdevs.Devices {
+		if d.allocClass() != "log" {
+			data.Devices = append(data.Devices, d)
+		}
+	}
+	data.Walk(func(v, parent *VDevTree) error {
+		switch {
+		case v.Type == VDevTypeSpare && len(v.Devices) > 1:
+			orig := &v.Devices[0]
+			for _, s := range v.Devices[1:] {
+				inuse[s.Path] = true
+				if !opts.ReturnSpares || resilvering ||
+					orig.Stat.State != VDevStateHealthy ||
+					s.Stat.State != VDevStateHealthy {
+					continue
+				}
+				ev := SpareEvent{Failed: orig.Path, Spare: s.Path,
+					Err: pool.DeactivateSpare(s.Path)}
+				ev.Returned = ev.Err == nil
+				events = append(events, ev)
+			}
+			return SkipVDev
+		case v.Type == VDevTypeReplacing:
+			// device is already being replaced
+			return SkipVDev
+		case len(v.Devices) == 0 && len(v.Path) > 0 && v.isFailed():
+			failed = append(failed, v)
+		}
+		return nil
+	})
+
+	var available []string
+	for _, s := range vdevs.Spares {
+		if !inuse[s.Path] && s.Stat.State == VDevStateHealthy &&
+			s.Stat.Aux != VDevAuxSpared {
+			available = append(available, s.Path)
+		}
+	}
+	for _, f := range failed {
+		if len(available) == 0 {
+			break
+		}
+		ev := SpareEvent{Failed: f.Path}
+		// spare may be too small to replace the device, try the next one
+		for i, s := range available {
+			ev.Spare = s
+			if ev.Err = pool.ActivateSpare(f.Path, s); ev.Err == nil {
+				ev.Activated = true
+				available = append(available[:i], available[i+1:]...)
+				break
+			}
+		}
+		events = append(events, ev)
+	}
+	return
+}
+
+// WatchSpares periodically opens pool with given name and runs CheckSpares
+// on it until ctx is done. Function fn is called for every spare event.
+// Failures to open or check the pool, which may be transient (e.g. pool
+// being imported), are passed to fn as events with only Err set and
+// watching continues. Returns when ctx is done, or error right away if
+// interval is not positive or fn is nil.
+func WatchSpares(ctx context.Context, name string, interval time.Duration,
+	opts SpareCheckOptions, fn func(SpareEvent)) (err error) {
+	if interval <= 0 {
+		err = fmt.Errorf("Invalid spare watch interval %v", interval)
+		return
+	}
+	if fn == nil {
+		err = errors.New("Spare event function is nil")
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		events, e := checkPoolSpares(name, opts)
+		if e != nil {
+			fn(SpareEvent{Err: e})
+		}
+		for _, ev := range events {
+			fn(ev)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func checkPoolSpares(name string, opts SpareCheckOptions) (events []SpareEvent, err error) {
+	pool, err := PoolOpen(name)
+	if err != nil {
+		return
+	}
+	defer pool.Close()
+	return pool.CheckSpares(opts)
+}
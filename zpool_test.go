@@ -1,9 +1,11 @@
 package zfs_test
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -366,6 +368,17 @@ func zpoolTestPoolVDevTree(t *testing.T) {
 	fmt.Printf("%-30s | %-10s | %-10s | %s\n", "NAME", "TYPE", "STATE", "PATH")
 	println("---------------------------------------------------------------")
 	printVDevTree(vdevs, "")
+	// path is reported for all leaf devices, not only missing ones
+	if len(vdevs.Devices) != 1 || len(vdevs.Devices[0].Devices) != 2 ||
+		vdevs.Devices[0].Devices[0].Path != s1path ||
+		vdevs.Devices[0].Devices[1].Path != s2path {
+		t.Errorf("Unexpected device paths: %+v", vdevs.Devices)
+		return
+	}
+	if len(vdevs.Spares) != 1 || vdevs.Spares[0].Path != s3path {
+		t.Errorf("Unexpected spare paths: %+v", vdevs.Spares)
+		return
+	}
 	print("PASS\n\n")
 }
 
@@ -403,6 +416,829 @@ func zpoolTestInitialization(t *testing.T) {
 	print("PASS\n\n")
 }
 
+// Create pool with dRAID data device and special mirror device, and check
+// that layout is reported back the same way in VDevTree
+func TestPoolCreateDraidSpecial(t *testing.T) {
+	println("TEST PoolCreate (draid, special) ... ")
+	pname := TSTPoolName + "_CLASSES"
+	var paths []string
+	defer func() {
+		for _, p := range paths {
+			removeVDisk(p)
+		}
+	}()
+	for i := 0; i < 6; i++ {
+		path, err := CreateTmpSparse("zfs_test_", 0x40000000)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		paths = append(paths, path)
+	}
+
+	var vdev zfs.VDevTree
+	var ddevs, sdevs []zfs.VDevTree
+	for _, p := range paths[:4] {
+		ddevs = append(ddevs, zfs.VDevTree{Type: zfs.VDevTypeFile, Path: p})
+	}
+	for _, p := range paths[4:] {
+		sdevs = append(sdevs, zfs.VDevTree{Type: zfs.VDevTypeFile, Path: p})
+	}
+	vdev.Devices = []zfs.VDevTree{
+		{Type: zfs.VDevTypeDraid, Parity: 1, DraidData: 2, DraidSpares: 1,
+			Devices: ddevs},
+		{Type: zfs.VDevTypeMirror, AllocBias: zfs.VDevAllocBiasSpecial,
+			Devices: sdevs},
+	}
+
+	fsprops := make(map[zfs.Prop]string)
+	fsprops[zfs.DatasetPropMountpoint] = "none"
+	pool, err := zfs.PoolCreate(pname, vdev, make(map[string]string),
+		make(map[zfs.Prop]string), fsprops)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer func() {
+		pool.Destroy(pname)
+		pool.Close()
+	}()
+
+	vdevs, err := pool.VDevTree()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	printVDevTree(vdevs, "")
+	if len(vdevs.Devices) != 2 {
+		t.Errorf("Expected 2 top level devices, got %d", len(vdevs.Devices))
+		return
+	}
+	draid := vdevs.Devices[0]
+	if draid.Type != zfs.VDevTypeDraid || draid.Parity != 1 ||
+		draid.DraidData != 2 || draid.DraidSpares != 1 ||
+		draid.DraidChildren != 4 {
+		t.Errorf("Unexpected dRAID layout: %+v", draid)
+		return
+	}
+	if vdevs.Devices[1].AllocBias != zfs.VDevAllocBiasSpecial {
+		t.Errorf("Expected special allocation class, got '%s'",
+			vdevs.Devices[1].AllocBias)
+		return
+	}
+	// distributed spare is listed among spares, but not in specification
+	spec, err := vdevs.Spec()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err = zfs.ParseVDevSpec(spec); err != nil || strings.Contains(spec, "spare") {
+		t.Errorf("Unexpected specification of dRAID pool %q: %v", spec, err)
+		return
+	}
+	print("PASS\n\n")
+}
+
+func TestPoolCreateDryRun(t *testing.T) {
+	println("TEST PoolCreateDryRun ... ")
+	var paths []string
+	defer func() {
+		for _, p := range paths {
+			removeVDisk(p)
+		}
+	}()
+	for i := 0; i < 3; i++ {
+		path, err := CreateTmpSparse("zfs_test_", 0x40000000)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		paths = append(paths, path)
+	}
+
+	var vdev zfs.VDevTree
+	var mdevs []zfs.VDevTree
+	for _, p := range paths[:2] {
+		mdevs = append(mdevs, zfs.VDevTree{Type: zfs.VDevTypeFile, Path: p})
+	}
+	vdev.Devices = []zfs.VDevTree{{Type: zfs.VDevTypeMirror, Devices: mdevs}}
+	vdev.Spares = []zfs.VDevTree{{Type: zfs.VDevTypeFile, Path: paths[2]}}
+
+	props := make(map[zfs.Prop]string)
+	props[zfs.PoolPropAshift] = "12"
+	plan, err := zfs.PoolCreateDryRun(TSTPoolName+"_DRYRUN", vdev,
+		make(map[string]string), props)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	printVDevTree(plan.VDevs, "")
+	if plan.Ashift != 12 {
+		t.Errorf("Expected ashift 12, got %d", plan.Ashift)
+	}
+	if plan.Features["large_dnode"] != zfs.FENABLED {
+		t.Error("Expected default features to be enabled")
+	}
+	if len(plan.VDevs.Devices) != 1 || len(plan.VDevs.Devices[0].Devices) != 2 ||
+		plan.VDevs.Devices[0].Devices[1].Path != paths[1] {
+		t.Errorf("Unexpected layout: %+v", plan.VDevs)
+	}
+	if plan.VDevs.Devices[0].Devices[0].WholeDisk != zfs.VDevWholeDiskOff {
+		t.Error("Expected file device not to be whole disk")
+	}
+	if len(plan.VDevs.Spares) != 1 || plan.VDevs.Spares[0].Path != paths[2] {
+		t.Errorf("Unexpected spares: %+v", plan.VDevs.Spares)
+	}
+	if _, err = zfs.PoolOpen(TSTPoolName + "_DRYRUN"); err == nil {
+		t.Error("Dry run should not create the pool")
+		return
+	}
+
+	// without ashift property it is left to ZFS to detect
+	plan, err = zfs.PoolCreateDryRun(TSTPoolName+"_DRYRUN", vdev,
+		make(map[string]string), make(map[zfs.Prop]string))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if plan.Ashift != 0 {
+		t.Errorf("Expected ashift 0 (auto detect), got %d", plan.Ashift)
+		return
+	}
+	print("PASS\n\n")
+}
+
+// Create pool with cache device and check that it is created as cache
+// device, not as spare
+func TestPoolCreateL2Cache(t *testing.T) {
+	println("TEST PoolCreate (l2cache) ... ")
+	pname := TSTPoolName + "_L2CACHE"
+	var paths []string
+	defer func() {
+		for _, p := range paths {
+			removeVDisk(p)
+		}
+	}()
+	for i := 0; i < 2; i++ {
+		path, err := CreateTmpSparse("zfs_test_", 0x40000000)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		paths = append(paths, path)
+	}
+	var vdev zfs.VDevTree
+	vdev.Devices = []zfs.VDevTree{{Type: zfs.VDevTypeFile, Path: paths[0]}}
+	vdev.L2Cache = []zfs.VDevTree{{Type: zfs.VDevTypeFile, Path: paths[1]}}
+
+	fsprops := make(map[zfs.Prop]string)
+	fsprops[zfs.DatasetPropMountpoint] = "none"
+	pool, err := zfs.PoolCreate(pname, vdev, make(map[string]string),
+		make(map[zfs.Prop]string), fsprops)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer func() {
+		pool.Destroy(pname)
+		pool.Close()
+	}()
+
+	vdevs, err := pool.VDevTree()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	printVDevTree(vdevs, "")
+	if len(vdevs.Spares) != 0 {
+		t.Errorf("Cache device created as spare: %+v", vdevs.Spares)
+		return
+	}
+	if len(vdevs.L2Cache) != 1 || vdevs.L2Cache[0].Path != paths[1] {
+		t.Errorf("Unexpected cache devices: %+v", vdevs.L2Cache)
+		return
+	}
+	print("PASS\n\n")
+}
+
+// Create pool with log device, dry run first, and check that it is created
+// as log device, not as another top level data device
+func TestPoolCreateLogs(t *testing.T) {
+	println("TEST PoolCreate (logs) ... ")
+	pname := TSTPoolName + "_LOGS"
+	var paths []string
+	defer func() {
+		for _, p := range paths {
+			removeVDisk(p)
+		}
+	}()
+	for i := 0; i < 2; i++ {
+		path, err := CreateTmpSparse("zfs_test_", 0x40000000)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		paths = append(paths, path)
+	}
+	var vdev zfs.VDevTree
+	vdev.Devices = []zfs.VDevTree{{Type: zfs.VDevTypeFile, Path: paths[0]}}
+	vdev.Logs = &zfs.VDevTree{Type: zfs.VDevTypeFile, Path: paths[1]}
+
+	plan, err := zfs.PoolCreateDryRun(pname, vdev, make(map[string]string),
+		make(map[zfs.Prop]string))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(plan.VDevs.Devices) != 1 || plan.VDevs.Logs == nil ||
+		plan.VDevs.Logs.Path != paths[1] {
+		t.Errorf("Unexpected dry run layout: %+v", plan.VDevs)
+		return
+	}
+
+	fsprops := make(map[zfs.Prop]string)
+	fsprops[zfs.DatasetPropMountpoint] = "none"
+	pool, err := zfs.PoolCreate(pname, vdev, make(map[string]string),
+		make(map[zfs.Prop]string), fsprops)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer func() {
+		pool.Destroy(pname)
+		pool.Close()
+	}()
+
+	vdevs, err := pool.VDevTree()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	printVDevTree(vdevs, "")
+	if len(vdevs.Devices) != 1 || vdevs.Devices[0].Path != paths[0] {
+		t.Errorf("Unexpected data devices: %+v", vdevs.Devices)
+		return
+	}
+	if vdevs.Logs == nil || vdevs.Logs.Path != paths[1] {
+		t.Errorf("Unexpected log device: %+v", vdevs.Logs)
+		return
+	}
+	print("PASS\n\n")
+}
+
+func TestPoolFaultDegrade(t *testing.T) {
+	println("TEST Pool Fault/Degrade ... ")
+	pname := TSTPoolName + "_FAULT"
+	var paths []string
+	defer func() {
+		for _, p := range paths {
+			removeVDisk(p)
+		}
+	}()
+	for i := 0; i < 2; i++ {
+		path, err := CreateTmpSparse("zfs_test_", 0x40000000)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		paths = append(paths, path)
+	}
+	vdev, err := zfs.ParseVDevSpec("mirror " + paths[0] + " " + paths[1])
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	fsprops := make(map[zfs.Prop]string)
+	fsprops[zfs.DatasetPropMountpoint] = "none"
+	pool, err := zfs.PoolCreate(pname, vdev, make(map[string]string),
+		make(map[zfs.Prop]string), fsprops)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer func() {
+		pool.Destroy(pname)
+		pool.Close()
+	}()
+
+	state := func(path string) (s zfs.VDevState) {
+		pool.RefreshStats()
+		vdevs, err := pool.VDevTree()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if d := vdevs.FindByPath(path); d != nil {
+			s = d.Stat.State
+		}
+		return
+	}
+	vdevs, err := pool.VDevTree()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	guid := vdevs.FindByPath(paths[1]).GUID
+
+	if err = pool.Degrade(guid, zfs.VDevAuxExternal); err != nil {
+		t.Error(err)
+		return
+	}
+	if s := state(paths[1]); s != zfs.VDevStateDegraded {
+		t.Errorf("Expected degraded device, got state %d", s)
+	}
+	if err = pool.Fault(guid, zfs.VDevAuxExternal); err != nil {
+		t.Error(err)
+		return
+	}
+	if s := state(paths[1]); s != zfs.VDevStateFaulted {
+		t.Errorf("Expected faulted device, got state %d", s)
+	}
+	if err = pool.Clear(paths[1]); err != nil {
+		t.Error(err)
+		return
+	}
+	if s := state(paths[1]); s != zfs.VDevStateHealthy {
+		t.Errorf("Expected healthy device after clear, got state %d", s)
+	}
+
+	print("PASS\n\n")
+}
+
+func TestPoolClearWithPolicy(t *testing.T) {
+	println("TEST Pool ClearWithPolicy ... ")
+	pname := TSTPoolName + "_REWIND"
+	var paths []string
+	defer func() {
+		for _, p := range paths {
+			removeVDisk(p)
+		}
+	}()
+	for i := 0; i < 4; i++ {
+		path, err := CreateTmpSparse("zfs_test_", 0x40000000)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		paths = append(paths, path)
+	}
+	vdev, err := zfs.ParseVDevSpec("mirror " + paths[0] + " " + paths[1] +
+		" " + paths[2] + " spare " + paths[3])
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	fsprops := make(map[zfs.Prop]string)
+	fsprops[zfs.DatasetPropMountpoint] = "none"
+	pool, err := zfs.PoolCreate(pname, vdev, make(map[string]string),
+		make(map[zfs.Prop]string), fsprops)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer func() {
+		pool.Destroy(pname)
+		pool.Close()
+	}()
+
+	state := func(path string) (s zfs.VDevState) {
+		pool.RefreshStats()
+		vdevs, err := pool.VDevTree()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if d := vdevs.FindByPath(path); d != nil {
+			s = d.Stat.State
+		}
+		return
+	}
+	vdevs, err := pool.VDevTree()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err = pool.Degrade(vdevs.FindByPath(paths[0]).GUID, zfs.VDevAuxExternal); err != nil {
+		t.Error(err)
+		return
+	}
+	if err = pool.Fault(vdevs.FindByPath(paths[1]).GUID, zfs.VDevAuxExternal); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// only given device is cleared, healthy pool is not rewound
+	result, err := pool.ClearWithPolicy(paths[1], zfs.PoolDoRewind, false)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if result != (zfs.PoolRewindResult{}) {
+		t.Errorf("Expected empty rewind result, got %+v", result)
+	}
+	if s := state(paths[1]); s != zfs.VDevStateHealthy {
+		t.Errorf("Expected healthy device after clear, got state %d", s)
+	}
+	if s := state(paths[0]); s != zfs.VDevStateDegraded {
+		t.Errorf("Expected other device to stay degraded, got state %d", s)
+	}
+
+	if result, err = pool.ClearWithPolicy("", zfs.PoolDoRewind, true); err != nil {
+		t.Error(err)
+	} else if result != (zfs.PoolRewindResult{}) {
+		t.Errorf("Expected empty dry run result, got %+v", result)
+	}
+	if s := state(paths[0]); s != zfs.VDevStateDegraded {
+		t.Errorf("Expected dry run to leave device degraded, got state %d", s)
+	}
+
+	// unknown and spare devices are rejected before anything is cleared
+	if _, err = pool.ClearWithPolicy("/nonexistent", zfs.PoolDoRewind, false); err == nil {
+		t.Error("Expected error clearing nonexistent device")
+	}
+	if _, err = pool.ClearWithPolicy(paths[3], zfs.PoolDoRewind, false); err == nil {
+		t.Error("Expected error clearing spare device")
+	}
+	if s := state(paths[0]); s != zfs.VDevStateDegraded {
+		t.Errorf("Expected device to stay degraded after failed clear, got state %d", s)
+	}
+
+	if err = pool.Clear(""); err != nil {
+		t.Error(err)
+		return
+	}
+	if s := state(paths[0]); s != zfs.VDevStateHealthy {
+		t.Errorf("Expected healthy device after pool clear, got state %d", s)
+	}
+	print("PASS\n\n")
+}
+
+func TestPoolHotSpares(t *testing.T) {
+	println("TEST Pool hot spares ... ")
+	pname := TSTPoolName + "_SPARES"
+	var paths []string
+	defer func() {
+		for _, p := range paths {
+			removeVDisk(p)
+		}
+	}()
+	for i := 0; i < 5; i++ {
+		path, err := CreateTmpSparse("zfs_test_", 0x40000000)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		paths = append(paths, path)
+	}
+	vdev, err := zfs.ParseVDevSpec("mirror " + paths[0] + " " + paths[1] +
+		" spare " + paths[2] + " log mirror " + paths[3] + " " + paths[4])
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	fsprops := make(map[zfs.Prop]string)
+	fsprops[zfs.DatasetPropMountpoint] = "none"
+	pool, err := zfs.PoolCreate(pname, vdev, make(map[string]string),
+		make(map[zfs.Prop]string), fsprops)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer func() {
+		pool.Destroy(pname)
+		pool.Close()
+	}()
+
+	vdevs, err := pool.VDevTree()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err = pool.Fault(vdevs.FindByPath(paths[1]).GUID, zfs.VDevAuxExternal); err != nil {
+		t.Error(err)
+		return
+	}
+	// log devices are not spared
+	if err = pool.Fault(vdevs.FindByPath(paths[3]).GUID, zfs.VDevAuxExternal); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// run single check of watcher
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var events []zfs.SpareEvent
+	err = zfs.WatchSpares(ctx, pname, time.Second, zfs.SpareCheckOptions{},
+		func(ev zfs.SpareEvent) {
+			events = append(events, ev)
+		})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(events) != 1 || !events[0].Activated || events[0].Err != nil ||
+		events[0].Failed != paths[1] || events[0].Spare != paths[2] {
+		t.Errorf("Expected spare activation, got %+v", events)
+		return
+	}
+	if vdevs, err = pool.VDevTree(); err != nil {
+		t.Error(err)
+		return
+	}
+	if d := vdevs.FindByPath(paths[2]); d == nil || vdevs.Parent(d).Type != zfs.VDevTypeSpare {
+		t.Error("Expected spare to be in use")
+		return
+	}
+
+	// spare is returned only if asked to, once device is healthy and
+	// resilvered
+	if err = pool.Clear(paths[1]); err != nil {
+		t.Error(err)
+		return
+	}
+	if err = pool.Wait(context.Background(), zfs.PoolWaitResilver, nil); err != nil {
+		t.Error(err)
+		return
+	}
+	if events, err = pool.CheckSpares(zfs.SpareCheckOptions{}); err != nil || len(events) != 0 {
+		t.Errorf("Expected spare to stay in use, got %+v, %v", events, err)
+		return
+	}
+	if events, err = pool.CheckSpares(zfs.SpareCheckOptions{ReturnSpares: true}); err != nil {
+		t.Error(err)
+		return
+	}
+	if len(events) != 1 || !events[0].Returned || events[0].Err != nil {
+		t.Errorf("Expected spare to be returned, got %+v", events)
+		return
+	}
+	if vdevs, err = pool.VDevTree(); err != nil {
+		t.Error(err)
+		return
+	}
+	if d := vdevs.FindByPath(paths[2]); d == nil || vdevs.TopLevel(d) != d {
+		t.Error("Expected spare to be available")
+		return
+	}
+	print("PASS\n\n")
+}
+
+func TestWatchSparesMissingPool(t *testing.T) {
+	println("TEST WatchSpares (missing pool) ... ")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var events []zfs.SpareEvent
+	err := zfs.WatchSpares(ctx, TSTPoolName+"_MISSING", time.Second, zfs.SpareCheckOptions{},
+		func(ev zfs.SpareEvent) {
+			events = append(events, ev)
+		})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(events) != 1 || events[0].Err == nil || events[0].Activated {
+		t.Errorf("Expected error event of missing pool, got %+v", events)
+		return
+	}
+	print("PASS\n\n")
+}
+
+func TestWatchSparesInvalid(t *testing.T) {
+	println("TEST WatchSpares (invalid arguments) ... ")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := zfs.WatchSpares(ctx, TSTPoolName, 0, zfs.SpareCheckOptions{},
+		func(ev zfs.SpareEvent) {}); err == nil {
+		t.Error("Expected error watching spares with zero interval")
+	}
+	if err := zfs.WatchSpares(ctx, TSTPoolName, -time.Second, zfs.SpareCheckOptions{},
+		func(ev zfs.SpareEvent) {}); err == nil {
+		t.Error("Expected error watching spares with negative interval")
+	}
+	if err := zfs.WatchSpares(ctx, TSTPoolName, time.Second, zfs.SpareCheckOptions{},
+		nil); err == nil {
+		t.Error("Expected error watching spares with nil function")
+	}
+	print("PASS\n\n")
+}
+
+func TestPoolReguidSyncLabelClear(t *testing.T) {
+	println("TEST Pool Reguid, Sync and LabelClear ... ")
+	pname := TSTPoolName + "_REGUID"
+	path, err := CreateTmpSparse("zfs_test_", 0x40000000)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer removeVDisk(path)
+	vdev, err := zfs.ParseVDevSpec(path)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	fsprops := make(map[zfs.Prop]string)
+	fsprops[zfs.DatasetPropMountpoint] = "none"
+	pool, err := zfs.PoolCreate(pname, vdev, make(map[string]string),
+		make(map[zfs.Prop]string), fsprops)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	exported := false
+	defer func() {
+		if !exported {
+			pool.Destroy(pname)
+		}
+		pool.Close()
+	}()
+
+	guid := pool.Properties[zfs.PoolPropGUID].Value
+	if err = pool.Reguid(); err != nil {
+		t.Error(err)
+		return
+	}
+	if pool.Properties[zfs.PoolPropGUID].Value == guid {
+		t.Error("Expected new pool GUID after reguid")
+	}
+	if err = pool.Sync(); err != nil {
+		t.Error(err)
+		return
+	}
+	if err = zfs.LabelClear(path, true); err == nil {
+		t.Error("LabelClear should fail on device of active pool")
+	}
+	if err = pool.Export(false, "export "+pname); err != nil {
+		t.Error(err)
+		return
+	}
+	exported = true
+	if err = zfs.LabelClear(path, false); err == nil {
+		t.Error("LabelClear should require force on device of exported pool")
+	}
+	if err = zfs.LabelClear(path, true); err != nil {
+		t.Error(err)
+		return
+	}
+	if imported, err := zfs.PoolImport(pname, []string{"/tmp"}); err == nil {
+		t.Error("Pool should not be importable after LabelClear")
+		imported.Destroy(pname)
+		imported.Close()
+	}
+	print("PASS\n\n")
+}
+
+func TestReadLabel(t *testing.T) {
+	println("TEST ReadLabel ... ")
+	pname := TSTPoolName + "_LABEL"
+	var paths []string
+	defer func() {
+		for _, p := range paths {
+			removeVDisk(p)
+		}
+	}()
+	for i := 0; i < 3; i++ {
+		path, err := CreateTmpSparse("zfs_test_", 0x40000000)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		paths = append(paths, path)
+	}
+
+	// unused device has no labels
+	labels, err := zfs.ReadLabel(paths[2])
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(labels) != 4 || labels.Valid() != 0 {
+		t.Errorf("Expected no valid labels on unused device, got %d", labels.Valid())
+		return
+	}
+
+	vdev, err := zfs.ParseVDevSpec("mirror " + paths[0] + " " + paths[1])
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	fsprops := make(map[zfs.Prop]string)
+	fsprops[zfs.DatasetPropMountpoint] = "none"
+	pool, err := zfs.PoolCreate(pname, vdev, make(map[string]string),
+		make(map[zfs.Prop]string), fsprops)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer func() {
+		pool.Destroy(pname)
+		pool.Close()
+	}()
+	vdevs, err := pool.VDevTree()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err = pool.Export(false, "export "+pname); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if labels, err = zfs.ReadLabel(paths[1]); err != nil {
+		t.Error(err)
+		return
+	}
+	if labels.Valid() != 4 {
+		for _, l := range labels {
+			t.Errorf("Label %d: %v", l.Index, l.Err)
+		}
+		return
+	}
+	label, err := labels.Latest()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	d := vdevs.FindByPath(paths[1])
+	if label.PoolName != pname || label.State != zfs.PoolStateExported ||
+		label.GUID != d.GUID || label.TopGUID != vdevs.TopLevel(d).GUID {
+		t.Errorf("Unexpected label %+v", label)
+	}
+	if label.VDevs.Type != zfs.VDevTypeMirror || len(label.VDevs.Devices) != 2 {
+		t.Errorf("Unexpected label vdev tree %+v", label.VDevs)
+	}
+	if pool, err = zfs.PoolImport(pname, []string{"/tmp"}); err != nil {
+		t.Error(err)
+		return
+	}
+	print("PASS\n\n")
+}
+
+func TestPoolWait(t *testing.T) {
+	println("TEST Pool Wait ... ")
+	pname := TSTPoolName + "_WAIT"
+	path, err := CreateTmpSparse("zfs_test_", 0x40000000)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer removeVDisk(path)
+	vdev, err := zfs.ParseVDevSpec(path)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	fsprops := make(map[zfs.Prop]string)
+	fsprops[zfs.DatasetPropMountpoint] = "none"
+	pool, err := zfs.PoolCreate(pname, vdev, make(map[string]string),
+		make(map[zfs.Prop]string), fsprops)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer func() {
+		pool.Destroy(pname)
+		pool.Close()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	// nothing to wait for
+	if err = pool.Wait(ctx, zfs.PoolWaitScrub, nil); err != nil {
+		t.Error(err)
+		return
+	}
+	if err = pool.Initialize(); err != nil {
+		t.Error(err)
+		return
+	}
+	// canceled waits share blocked wait with the one below
+	canceled, cancelWaits := context.WithCancel(context.Background())
+	cancelWaits()
+	for i := 0; i < 3; i++ {
+		if err = pool.Wait(canceled, zfs.PoolWaitInitialize, nil); err != nil &&
+			err != context.Canceled {
+			t.Error(err)
+			return
+		}
+	}
+	err = pool.Wait(ctx, zfs.PoolWaitInitialize, func(p zfs.PoolWaitProgress) {
+		fmt.Printf("initialized %.1f%%\n", p.Percent())
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	p, err := pool.Progress(zfs.PoolWaitInitialize)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if p.Remaining != 0 || p.Percent() != 100 {
+		t.Errorf("Expected initialization to be completed, got %+v", p)
+	}
+	print("PASS\n\n")
+}
+
 /* ------------------------------------------------------------------------- */
 // EXAMPLES:
 
@@ -2,10 +2,14 @@
 #include <memory.h>
 #include <string.h>
 #include <stdio.h>
+#include <errno.h>
+#include <fcntl.h>
+#include <unistd.h>
 #include <sys/fs/zfs.h>
 
 #include "common.h"
 #include "zpool.h"
+#include "zfs.h"
 
 
 uint64_t set_zpool_vdev_online(zpool_list_t *pool, const char *path, int flags) {
@@ -35,3 +39,180 @@ int set_zpool_vdev_offline(zpool_list_t *pool, const char *path, boolean_t istmp
 	return ret;
 }
 
+int set_zpool_vdev_fault(zpool_list_t *pool, uint64_t guid, uint64_t aux) {
+	return zpool_vdev_fault(pool->zph, guid, (vdev_aux_t)aux);
+}
+
+int set_zpool_vdev_degrade(zpool_list_t *pool, uint64_t guid, uint64_t aux) {
+	return zpool_vdev_degrade(pool->zph, guid, (vdev_aux_t)aux);
+}
+
+int do_zpool_vdev_attach(zpool_list_t *pool, const char *old_disk, const char *new_disk, nvlist_ptr nvroot, int replacing) {
+	return zpool_vdev_attach(pool->zph, old_disk, new_disk, nvroot, replacing, B_FALSE);
+}
+
+int do_zpool_vdev_detach(zpool_list_t *pool, const char *path) {
+	return zpool_vdev_detach(pool->zph, path);
+}
+
+/*
+ * Clear pool or device errors with rewind policy, the way zpool clear does.
+ * Returns -1 with libzfs error set on failure, or errno.
+ */
+int do_zpool_clear(zpool_list_t *pool, const char *device, uint32_t rewind_policy) {
+	nvlist_t *policy = NULL;
+	int ret = 0;
+	if (nvlist_alloc(&policy, NV_UNIQUE_NAME, 0) != 0 ||
+	    nvlist_add_uint32(policy, ZPOOL_LOAD_REWIND_POLICY, rewind_policy) != 0) {
+		nvlist_free(policy);
+		return (ENOMEM);
+	}
+	if (zpool_clear(pool->zph, device, policy) != 0)
+		ret = -1;
+	nvlist_free(policy);
+	return (ret);
+}
+
+/*
+ * Clear pool or device errors with rewind policy in single ZFS_IOC_CLEAR
+ * ioctl, the same way zpool_clear does, but instead of printing rewind
+ * results return them. On success load info of the pool
+ * (ZPOOL_CONFIG_LOAD_INFO), if there is any, is stored to loadinfo and
+ * should be freed by caller. Returns 0 or errno, ENODEV if device is not
+ * in pool and ENOTSUP if it is a hot spare.
+ */
+int do_zpool_clear_rewind(zpool_list_t *pool, const char *device, uint32_t rewind_policy, nvlist_ptr *loadinfo) {
+	zfs_cmd_t zc;
+	nvlist_t *policy = NULL, *config = NULL, *info = NULL;
+	char *packed = NULL;
+	size_t packedsize = 0;
+	uint64_t dstsize = 64 * 1024;
+	int ret = 0;
+
+	*loadinfo = NULL;
+	memset(&zc, 0, sizeof (zc));
+	strlcpy(zc.zc_name, zpool_get_name(pool->zph), sizeof (zc.zc_name));
+	if (device != NULL) {
+		boolean_t spare, l2cache;
+		nvlist_t *tgt = zpool_find_vdev(pool->zph, device, &spare,
+		    &l2cache, NULL);
+		if (tgt == NULL ||
+		    nvlist_lookup_uint64(tgt, ZPOOL_CONFIG_GUID, &zc.zc_guid) != 0)
+			return (ENODEV);
+		/* same as zpool_clear, errors of hot spares are not cleared */
+		if (spare)
+			return (ENOTSUP);
+	}
+	zc.zc_cookie = rewind_policy;
+	if (nvlist_alloc(&policy, NV_UNIQUE_NAME, 0) != 0 ||
+	    nvlist_add_uint32(policy, ZPOOL_LOAD_REWIND_POLICY, rewind_policy) != 0 ||
+	    nvlist_pack(policy, &packed, &packedsize, NV_ENCODE_NATIVE, 0) != 0) {
+		nvlist_free(policy);
+		return (ENOMEM);
+	}
+	nvlist_free(policy);
+	zc.zc_nvlist_src = (uint64_t)(uintptr_t)packed;
+	zc.zc_nvlist_src_size = packedsize;
+
+	for (;;) {
+		char *dst = calloc(1, dstsize);
+		if (dst == NULL) {
+			ret = ENOMEM;
+			break;
+		}
+		zc.zc_nvlist_dst = (uint64_t)(uintptr_t)dst;
+		zc.zc_nvlist_dst_size = dstsize;
+		ret = zfs_ioctl(libzfsHandle, ZFS_IOC_CLEAR, &zc) == 0 ? 0 : errno;
+		if (ret == ENOMEM) {
+			/* config does not fit, kernel reports required size */
+			free(dst);
+			dstsize = zc.zc_nvlist_dst_size > dstsize ?
+			    zc.zc_nvlist_dst_size : dstsize * 2;
+			continue;
+		}
+		/* dry run reports results even if pool can't be opened */
+		if ((rewind_policy & ZPOOL_TRY_REWIND) && ret != EPERM && ret != EACCES)
+			ret = 0;
+		if (ret == 0 && zc.zc_nvlist_dst_filled &&
+		    nvlist_unpack(dst, zc.zc_nvlist_dst_size, &config, 0) == 0) {
+			if (nvlist_lookup_nvlist(config, ZPOOL_CONFIG_LOAD_INFO, &info) == 0)
+				nvlist_dup(info, loadinfo, 0);
+			nvlist_free(config);
+		}
+		free(dst);
+		break;
+	}
+	free(packed);
+	return (ret);
+}
+
+int64_t get_rewind_time(nvlist_ptr loadinfo) {
+	int64_t loss = 0;
+	nvlist_lookup_int64(loadinfo, ZPOOL_CONFIG_REWIND_TIME, &loss);
+	return loss;
+}
+
+uint64_t get_load_time(nvlist_ptr loadinfo) {
+	uint64_t t = 0;
+	nvlist_lookup_uint64(loadinfo, ZPOOL_CONFIG_LOAD_TIME, &t);
+	return t;
+}
+
+uint64_t get_load_data_errors(nvlist_ptr loadinfo) {
+	uint64_t errors = 0;
+	nvlist_lookup_uint64(loadinfo, ZPOOL_CONFIG_LOAD_DATA_ERRORS, &errors);
+	return errors;
+}
+
+/*
+ * Clear ZFS labels from device the way zpool labelclear does. Labels of
+ * devices used by active pool are never cleared (EBUSY), force is required
+ * for members of exported or potentially active pools (EEXIST).
+ * Returns 0 or errno.
+ */
+int do_zpool_labelclear(const char *device, boolean_t force) {
+	char path[MAXPATHLEN];
+	pool_state_t state;
+	boolean_t inuse = B_FALSE;
+	char *name = NULL;
+	int fd, ret = 0;
+
+	if (device[0] == '/') {
+		strlcpy(path, device, sizeof (path));
+	} else if (zfs_resolve_shortname(device, path, sizeof (path)) != 0) {
+		return (ENOENT);
+	}
+	if ((fd = open(path, O_RDWR | O_CLOEXEC)) < 0)
+		return (errno);
+
+	if (zpool_in_use(libzfsHandle, fd, &state, &name, &inuse) != 0) {
+		ret = EIO;
+		goto out;
+	}
+	if (inuse) {
+		/* same as zpool labelclear, refuse states it doesn't know */
+		switch (state) {
+		default:
+		case POOL_STATE_ACTIVE:
+		case POOL_STATE_SPARE:
+		case POOL_STATE_L2CACHE:
+			ret = EBUSY;
+			goto out;
+		case POOL_STATE_EXPORTED:
+		case POOL_STATE_POTENTIALLY_ACTIVE:
+			if (!force) {
+				ret = EEXIST;
+				goto out;
+			}
+			break;
+		case POOL_STATE_DESTROYED:
+			break;
+		}
+	}
+	if (zpool_clear_label(fd) != 0)
+		ret = EIO;
+out:
+	free(name);
+	(void) close(fd);
+	return (ret);
+}
@@ -1,13 +1,17 @@
 package zfs
 
 // #include <stdlib.h>
+// #include <errno.h>
 // #include <libzfs.h>
 // #include "common.h"
 // #include "zpool.h"
 // #include "zfs.h"
 import "C"
 import (
+	"errors"
 	"fmt"
+	"syscall"
+	"time"
 	"unsafe"
 )
 
@@ -67,16 +71,197 @@ func (pool *Pool) offline(temp, force bool, devs ...string) (err error) {
 	return
 }
 
+// PoolRewindPolicy policy of pool rewind to earlier transaction group on
+// clear of pool errors, discarding last transactions to recover the pool
+type PoolRewindPolicy uint32
+
+// Pool rewind policies
+const (
+	PoolNoRewind      PoolRewindPolicy = C.ZPOOL_NO_REWIND      // no rewind, default behavior
+	PoolNeverRewind   PoolRewindPolicy = C.ZPOOL_NEVER_REWIND   // do not search for best txg or rewind
+	PoolTryRewind     PoolRewindPolicy = C.ZPOOL_TRY_REWIND     // search for best txg, but do not rewind
+	PoolDoRewind      PoolRewindPolicy = C.ZPOOL_DO_REWIND      // rewind to best txg within deferred frees
+	PoolExtremeRewind PoolRewindPolicy = C.ZPOOL_EXTREME_REWIND // allow extreme measures to find best txg
+)
+
+// PoolRewindResult result of clearing pool errors with rewind policy
+type PoolRewindResult struct {
+	// Pool state is (or with dry run would be) returned to LoadTime
+	LoadTime time.Time
+	// Approximate time span of discarded transactions
+	Discarded time.Duration
+	// Data errors found while verifying rewound pool
+	DataErrors uint64
+}
+
 // Clear - Clear all errors associated with a pool or a particular device.
 func (pool *Pool) Clear(device string) (err error) {
-	csdev := C.CString(device)
-	if len(device) == 0 {
-		csdev = nil
+	return pool.clear(device, PoolNoRewind)
+}
+
+// ClearWithPolicy clears all errors associated with a pool or a particular
+// device, and if pool can't be opened recovers it according to rewind
+// policy (zpool clear -F). With dryRun, combined with PoolDoRewind or
+// PoolExtremeRewind policy, it only reports if pool can be recovered and
+// how many seconds of transactions would be discarded, without rewinding
+// (zpool clear -Fn). Result is reported only for rewind policies and is
+// empty if pool doesn't need to be rewound.
+func (pool *Pool) ClearWithPolicy(device string, policy PoolRewindPolicy,
+	dryRun bool) (result PoolRewindResult, err error) {
+	if pool.list == nil {
+		err = errors.New(msgPoolIsNil)
+		return
+	}
+	if dryRun {
+		policy = PoolTryRewind | policy&PoolExtremeRewind
+	}
+	if policy&(PoolTryRewind|PoolDoRewind) == 0 {
+		err = pool.clear(device, policy)
+		return
+	}
+	var csdev *C.char
+	if len(device) > 0 {
+		csdev = C.CString(device)
+		defer C.free(unsafe.Pointer(csdev))
+	}
+	var loadinfo C.nvlist_ptr
+	switch r := C.do_zpool_clear_rewind(pool.list, csdev, C.uint32_t(policy), &loadinfo); r {
+	case 0:
+	case C.ENODEV:
+		err = fmt.Errorf("Cannot clear errors for %s: no such device in pool", device)
+		return
+	case C.ENOTSUP:
+		err = fmt.Errorf("Cannot clear errors for %s: device is reserved as a hot spare", device)
+		return
+	default:
+		err = fmt.Errorf("Pool clear failed: %s", syscall.Errno(r))
+		return
+	}
+	if loadinfo != nil {
+		defer C.nvlist_free(loadinfo)
+		if t := int64(C.get_load_time(loadinfo)); t > 0 {
+			result.LoadTime = time.Unix(t, 0)
+		}
+		result.Discarded = time.Duration(C.get_rewind_time(loadinfo)) * time.Second
+		result.DataErrors = uint64(C.get_load_data_errors(loadinfo))
+	}
+	return
+}
+
+// clear clears errors with policy without rewind, the way zpool clear does
+func (pool *Pool) clear(device string, policy PoolRewindPolicy) (err error) {
+	var csdev *C.char
+	if len(device) > 0 {
+		csdev = C.CString(device)
+		defer C.free(unsafe.Pointer(csdev))
+	}
+	switch r := C.do_zpool_clear(pool.list, csdev, C.uint32_t(policy)); r {
+	case 0:
+	case -1:
+		err = LastError()
+	default:
+		err = fmt.Errorf("Pool clear failed: %s", syscall.Errno(r))
+	}
+	return
+}
+
+// Fault forces device with given GUID into faulted state, aux is reason
+// of the fault, VDevAuxExternal, or VDevAuxExternalPersist to keep device
+// faulted across imports. Fault is cleared by Clear or Online.
+func (pool *Pool) Fault(guid uint64, aux VDevAux) (err error) {
+	if pool.list == nil {
+		err = errors.New(msgPoolIsNil)
+		return
 	}
-	if sc := C.do_zpool_clear(pool.list, csdev, C.ZPOOL_NO_REWIND); sc != 0 {
-		err = fmt.Errorf("Pool clear failed")
+	if r := C.set_zpool_vdev_fault(pool.list, C.uint64_t(guid), C.uint64_t(aux)); r != 0 {
+		err = LastError()
+	}
+	return
+}
+
+// Degrade forces device with given GUID into degraded state, aux is reason
+// of the degradation, usually VDevAuxExternal. Device remains in use but
+// ZFS stops relying on it where there is sufficient redundancy.
+func (pool *Pool) Degrade(guid uint64, aux VDevAux) (err error) {
+	if pool.list == nil {
+		err = errors.New(msgPoolIsNil)
+		return
+	}
+	if r := C.set_zpool_vdev_degrade(pool.list, C.uint64_t(guid), C.uint64_t(aux)); r != 0 {
+		err = LastError()
+	}
+	return
+}
+
+// ActivateSpare replaces failed device with hot spare of the pool. Spare
+// remains in use until DeactivateSpare returns it to the spares list, or
+// failed device is detached, what makes the replacement permanent.
+func (pool *Pool) ActivateSpare(failed, spare string) (err error) {
+	if pool.list == nil {
+		err = errors.New(msgPoolIsNil)
+		return
+	}
+	vdevs, err := pool.VDevTree()
+	if err != nil {
+		return
+	}
+	spares := VDevTree{Spares: vdevs.Spares}
+	sp := spares.FindByPath(spare)
+	if sp == nil {
+		err = fmt.Errorf("Device '%s' is not a spare of the pool", spare)
+		return
+	}
+	nvroot, _, err := buildPoolRoot(VDevTree{Devices: []VDevTree{
+		{Type: sp.Type, Path: sp.Path, WholeDisk: sp.WholeDisk}}}, nil)
+	if err != nil {
+		return
+	}
+	defer C.nvlist_free(nvroot)
+	csFailed := C.CString(failed)
+	defer C.free(unsafe.Pointer(csFailed))
+	csSpare := C.CString(sp.Path)
+	defer C.free(unsafe.Pointer(csSpare))
+	if r := C.do_zpool_vdev_attach(pool.list, csFailed, csSpare, nvroot, 1); r != 0 {
+		err = LastError()
+	}
+	return
+}
+
+// DeactivateSpare detaches hot spare in use from the failed device it
+// replaces and returns it to the spares list of the pool.
+func (pool *Pool) DeactivateSpare(spare string) (err error) {
+	if pool.list == nil {
+		err = errors.New(msgPoolIsNil)
+		return
+	}
+	csSpare := C.CString(spare)
+	defer C.free(unsafe.Pointer(csSpare))
+	if r := C.do_zpool_vdev_detach(pool.list, csSpare); r != 0 {
+		err = LastError()
+	}
+	return
+}
+
+// LabelClear removes ZFS label information from the device, e.g. before
+// reusing disk of destroyed pool in PoolCreate. Labels of devices that are
+// members of exported or potentially active pool are cleared only with
+// force, labels of devices used by active pool or pool in unknown state
+// never.
+func LabelClear(device string, force bool) (err error) {
+	csdev := C.CString(device)
+	defer C.free(unsafe.Pointer(csdev))
+	switch r := C.do_zpool_labelclear(csdev, booleanT(force)); r {
+	case 0:
+	case C.EBUSY:
+		err = fmt.Errorf("Device '%s' is used by active pool or pool in unknown state", device)
+	case C.EEXIST:
+		err = fmt.Errorf(
+			"Device '%s' is a member of exported or potentially active pool, use force to clear label",
+			device)
+	default:
+		err = fmt.Errorf("Failed to clear label on '%s': %s", device,
+			syscall.Errno(r))
 	}
-	C.free(unsafe.Pointer(csdev))
 	return
 }
 
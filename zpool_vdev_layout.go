@@ -0,0 +1,434 @@
+package zfs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// VDevIssueSeverity how serious is problem found in vdev layout
+type VDevIssueSeverity int
+
+// Vdev layout issue severities
+const (
+	VDevIssueWarning VDevIssueSeverity = iota // layout works, but probably not as intended
+	VDevIssueError                            // layout can not be used to create pool
+)
+
+// VDevIssue single problem found in vdev layout
+type VDevIssue struct {
+	Severity VDevIssueSeverity
+	Device   string // device in layout issue refers to, e.g. mirror-0/sdb
+	Message  string
+}
+
+// VDevValidation result of vdev layout validation
+type VDevValidation struct {
+	Warnings []VDevIssue
+	Errors   []VDevIssue
+}
+
+// VDevCapacity estimated capacity and redundancy of vdev layout
+type VDevCapacity struct {
+	Raw            uint64 // total size of data devices
+	Usable         uint64 // space left for data after redundancy
+	Special        uint64 // space of special and dedup allocation classes
+	Log            uint64 // space of log devices
+	FaultTolerance int    // devices that can fail in any top level device without data loss
+}
+
+// Maximum number of parity devices supported by raidz and dRAID
+const vdevMaxParity = 3
+
+func (s VDevIssueSeverity) String() string {
+	switch s {
+	case VDevIssueWarning:
+		return "WARNING"
+	case VDevIssueError:
+		return "ERROR"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+func (i VDevIssue) String() string {
+	if len(i.Device) == 0 {
+		return fmt.Sprintf("%s: %s", i.Severity, i.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", i.Severity, i.Device, i.Message)
+}
+
+// Ok returns true if there are no errors in layout
+func (v *VDevValidation) Ok() bool {
+	return len(v.Errors) == 0
+}
+
+// Err returns all errors found in layout as single error, or nil if
+// layout is valid
+func (v *VDevValidation) Err() error {
+	if v.Ok() {
+		return nil
+	}
+	msgs := make([]string, 0, len(v.Errors))
+	for _, e := range v.Errors {
+		msgs = append(msgs, e.String())
+	}
+	return errors.New("Invalid vdev specification: " + strings.Join(msgs, "; "))
+}
+
+func (v *VDevValidation) addWarning(device, format string, args ...interface{}) {
+	v.Warnings = append(v.Warnings, VDevIssue{Severity: VDevIssueWarning,
+		Device: device, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *VDevValidation) addError(device, format string, args ...interface{}) {
+	v.Errors = append(v.Errors, VDevIssue{Severity: VDevIssueError,
+		Device: device, Message: fmt.Sprintf(format, args...)})
+}
+
+// vdevLabel - name used to reference device in layout issues
+func vdevLabel(vdev VDevTree, index int) string {
+	if len(vdev.Name) > 0 {
+		return vdev.Name
+	}
+	if len(vdev.Path) > 0 {
+		return vdev.Path
+	}
+	return fmt.Sprintf("%s-%d", vdev.Type, index)
+}
+
+func (vdev *VDevTree) isLeaf() bool {
+	return vdev.Type == VDevTypeDisk || vdev.Type == VDevTypeFile
+}
+
+// replication - redundancy description of top level device used to compare
+// replication level of top level devices, e.g. "mirror 2", "raidz 1"
+func (vdev *VDevTree) replication() string {
+	switch vdev.Type {
+	case VDevTypeMirror:
+		return fmt.Sprintf("%d-way %s", len(vdev.Devices), vdev.Type)
+	case VDevTypeRaidz, VDevTypeDraid:
+		parity := vdev.Parity
+		if parity == 0 {
+			parity = 1
+		}
+		return fmt.Sprintf("%s%d", vdev.Type, parity)
+	default:
+		return "single device"
+	}
+}
+
+// faultTolerance - number of child devices top level device can loose
+func (vdev *VDevTree) faultTolerance() int {
+	switch vdev.Type {
+	case VDevTypeMirror:
+		return len(vdev.Devices) - 1
+	case VDevTypeRaidz, VDevTypeDraid:
+		if vdev.Parity == 0 {
+			return 1
+		}
+		return int(vdev.Parity)
+	default:
+		return 0
+	}
+}
+
+// allocClass - allocation class of top level device, "data", "special"
+// (including dedup) or "log"
+func (vdev *VDevTree) allocClass() string {
+	switch {
+	case vdev.Type == VDevTypeLog || vdev.AllocBias == VDevAllocBiasLog:
+		return "log"
+	case vdev.AllocBias == VDevAllocBiasSpecial || vdev.AllocBias == VDevAllocBiasDedup:
+		return "special"
+	default:
+		return "data"
+	}
+}
+
+// allocClasses - split top level devices on allocation classes
+func (vdev *VDevTree) allocClasses() (data, special, logs []VDevTree) {
+	for _, d := range vdev.Devices {
+		switch d.allocClass() {
+		case "log":
+			logs = append(logs, d)
+		case "special":
+			special = append(special, d)
+		default:
+			data = append(data, d)
+		}
+	}
+	if vdev.Logs != nil {
+		logs = append(logs, *vdev.Logs)
+	}
+	return
+}
+
+// ValidateVDevTree checks vdev layout intended for PoolCreate without
+// accessing any of the devices. Device sizes are compared only if Size is
+// set for leaf devices. Returned errors would make pool creation fail, while
+// warnings report layouts zpool command would refuse without force flag.
+func ValidateVDevTree(vdev VDevTree) (v VDevValidation) {
+	paths := make(map[string]string)
+	data, special, logs := vdev.allocClasses()
+	if len(data) == 0 {
+		v.addError("", "pool requires at least one data device")
+	}
+	for i, d := range vdev.Devices {
+		v.validateTopLevel(d, vdevLabel(d, i), paths)
+	}
+	if vdev.Logs != nil {
+		v.validateTopLevel(*vdev.Logs, vdevLabel(*vdev.Logs, len(vdev.Devices)), paths)
+	}
+	for _, d := range vdev.Spares {
+		v.validateAux(d, VDevTypeSpare, paths)
+	}
+	for _, d := range vdev.L2Cache {
+		v.validateAux(d, VDevTypeL2cache, paths)
+	}
+
+	// replication level should be the same for all top level devices
+	// within allocation class, labels are in order of allocClasses
+	labels := make(map[string][]string)
+	for i, d := range vdev.Devices {
+		class := d.allocClass()
+		labels[class] = append(labels[class], vdevLabel(d, i))
+	}
+	if vdev.Logs != nil {
+		labels["log"] = append(labels["log"], vdevLabel(*vdev.Logs, len(vdev.Devices)))
+	}
+	v.validateReplication(data, labels["data"], "data")
+	v.validateReplication(special, labels["special"], "special")
+	v.validateReplication(logs, labels["log"], "log")
+	if len(data) > 0 && len(special) > 0 {
+		if min := minFaultTolerance(special); min < minFaultTolerance(data) {
+			v.addWarning("", "special devices have lower redundancy (%d) than data devices (%d)",
+				min, minFaultTolerance(data))
+		}
+	}
+
+	// spares should be able to replace any data device
+	if len(vdev.Spares) > 0 && len(data) > 0 {
+		var largest uint64
+		for _, d := range data {
+			for _, leaf := range leafDevices(d) {
+				if leaf.Size > largest {
+					largest = leaf.Size
+				}
+			}
+		}
+		for i, s := range vdev.Spares {
+			if s.Size > 0 && s.Size < largest {
+				v.addWarning(vdevLabel(s, i), "spare is smaller than largest data device")
+			}
+		}
+	}
+	return
+}
+
+func (v *VDevValidation) validateTopLevel(vdev VDevTree, label string,
+	paths map[string]string) {
+	switch vdev.Type {
+	case VDevTypeDisk, VDevTypeFile:
+		v.validateLeaf(vdev, label, paths)
+		return
+	case VDevTypeMirror, VDevTypeRaidz, VDevTypeDraid, VDevTypeLog:
+	default:
+		v.addError(label, "unsupported top level device type '%s'", vdev.Type)
+		return
+	}
+	if len(vdev.Path) > 0 {
+		v.addError(label, "%s can not have path", vdev.Type)
+	}
+	if (vdev.Type == VDevTypeRaidz || vdev.Type == VDevTypeDraid) &&
+		vdev.Parity > vdevMaxParity {
+		v.addError(label, "%s supports at most %d parity devices", vdev.Type,
+			vdevMaxParity)
+		return
+	}
+	grouping, mindevs, maxdevs := vdev.isGrouping()
+	if grouping && (len(vdev.Devices) < mindevs || len(vdev.Devices) > maxdevs) {
+		v.addError(label, "%s supports no less than %d or more than %d devices",
+			vdev.Type, mindevs, maxdevs)
+	}
+	if vdev.Type == VDevTypeDraid {
+		if _, _, err := vdev.draidLayout(); err != nil {
+			v.addError(label, "%s", strings.TrimPrefix(err.Error(),
+				"Invalid vdev specification: "))
+		}
+	}
+
+	var size uint64
+	var types = make(map[VDevType]bool)
+	for i, d := range vdev.Devices {
+		dlabel := label + "/" + vdevLabel(d, i)
+		if !d.isLeaf() {
+			v.addError(dlabel, "%s can not be part of %s", d.Type, vdev.Type)
+			continue
+		}
+		v.validateLeaf(d, dlabel, paths)
+		types[d.Type] = true
+		if d.Size == 0 {
+			continue
+		}
+		if size > 0 && d.Size != size {
+			v.addWarning(label, "contains devices of different sizes")
+			size = 0
+			break
+		}
+		size = d.Size
+	}
+	if len(types) > 1 {
+		v.addWarning(label, "contains both files and devices")
+	}
+}
+
+func (v *VDevValidation) validateAux(vdev VDevTree, class VDevType,
+	paths map[string]string) {
+	label := string(class) + "/" + vdevLabel(vdev, 0)
+	if !vdev.isLeaf() {
+		v.addError(label, "%s device must be disk or file, not %s", class, vdev.Type)
+		return
+	}
+	v.validateLeaf(vdev, label, paths)
+}
+
+func (v *VDevValidation) validateLeaf(vdev VDevTree, label string,
+	paths map[string]string) {
+	if len(vdev.Devices) > 0 {
+		v.addError(label, "%s can not have child devices", vdev.Type)
+	}
+	if len(vdev.Path) == 0 {
+		v.addError(label, "%s requires path", vdev.Type)
+		return
+	}
+	if vdev.Type == VDevTypeFile && !strings.HasPrefix(vdev.Path, "/") {
+		v.addError(label, "file path must be absolute")
+	}
+	if prev, ok := paths[vdev.Path]; ok {
+		v.addError(label, "%s is already used as %s", vdev.Path, prev)
+		return
+	}
+	paths[vdev.Path] = label
+}
+
+// validateReplication - warn about top level devices of allocation class
+// with replication level different from the first one, as zpool refuses
+// such layout without force flag
+func (v *VDevValidation) validateReplication(vdevs []VDevTree, labels []string,
+	class string) {
+	if len(vdevs) < 2 {
+		return
+	}
+	first := vdevs[0].replication()
+	for i, d := range vdevs[1:] {
+		if rep := d.replication(); rep != first {
+			v.addWarning(labels[i+1],
+				"mismatched %s replication level: both %s and %s are present",
+				class, first, rep)
+		}
+	}
+}
+
+func minFaultTolerance(vdevs []VDevTree) (min int) {
+	min = -1
+	for _, d := range vdevs {
+		if ft := d.faultTolerance(); min < 0 || ft < min {
+			min = ft
+		}
+	}
+	return
+}
+
+func leafDevices(vdev VDevTree) (leaves []VDevTree) {
+	if vdev.isLeaf() {
+		return []VDevTree{vdev}
+	}
+	for _, d := range vdev.Devices {
+		leaves = append(leaves, leafDevices(d)...)
+	}
+	return
+}
+
+// usable - estimated space of top level device available for data
+func (vdev *VDevTree) usable() (raw, usable uint64, err error) {
+	leaves := leafDevices(*vdev)
+	if len(leaves) == 0 {
+		err = fmt.Errorf("%s has no devices", vdev.Type)
+		return
+	}
+	var min uint64
+	for _, l := range leaves {
+		if l.Size == 0 {
+			err = fmt.Errorf("size of device %s is unknown", l.Path)
+			return
+		}
+		if min == 0 || l.Size < min {
+			min = l.Size
+		}
+		raw += l.Size
+	}
+	n := uint64(len(leaves))
+	switch vdev.Type {
+	case VDevTypeMirror:
+		usable = min
+	case VDevTypeRaidz:
+		parity := uint64(vdev.faultTolerance())
+		if n <= parity {
+			err = fmt.Errorf("%s has not enough devices", vdev.Type)
+			return
+		}
+		usable = min * (n - parity)
+	case VDevTypeDraid:
+		var ndata uint
+		if ndata, _, err = vdev.draidLayout(); err != nil {
+			return
+		}
+		parity := uint64(vdev.faultTolerance())
+		usable = min * (n - uint64(vdev.DraidSpares)) * uint64(ndata) /
+			(uint64(ndata) + parity)
+	default:
+		usable = min
+	}
+	return
+}
+
+// VDevTreeCapacity estimates capacity and fault tolerance of vdev layout
+// intended for PoolCreate, based on Size of leaf devices. This is only
+// estimate, it doesn't account ZFS metadata, slop space and raidz padding.
+func VDevTreeCapacity(vdev VDevTree) (capacity VDevCapacity, err error) {
+	data, special, logs := vdev.allocClasses()
+	if len(data) == 0 {
+		err = errors.New("Pool requires at least one data device")
+		return
+	}
+	capacity.FaultTolerance = minFaultTolerance(data)
+	if len(special) > 0 {
+		if ft := minFaultTolerance(special); ft < capacity.FaultTolerance {
+			capacity.FaultTolerance = ft
+		}
+	}
+	for _, d := range data {
+		var raw, usable uint64
+		if raw, usable, err = d.usable(); err != nil {
+			return
+		}
+		capacity.Raw += raw
+		capacity.Usable += usable
+	}
+	for _, d := range special {
+		var usable uint64
+		if _, usable, err = d.usable(); err != nil {
+			return
+		}
+		capacity.Special += usable
+	}
+	for _, d := range logs {
+		var usable uint64
+		if _, usable, err = d.usable(); err != nil {
+			return
+		}
+		capacity.Log += usable
+	}
+	return
+}
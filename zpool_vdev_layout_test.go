@@ -0,0 +1,134 @@
+package zfs_test
+
+import (
+	"testing"
+
+	zfs "github.com/bicomsystems/go-libzfs"
+)
+
+const gib = uint64(1 << 30)
+
+func layoutDisks(size uint64, paths ...string) (vdevs []zfs.VDevTree) {
+	for _, p := range paths {
+		vdevs = append(vdevs, zfs.VDevTree{Type: zfs.VDevTypeDisk,
+			Path: p, Size: size})
+	}
+	return
+}
+
+func TestValidateVDevTree(t *testing.T) {
+	tests := []struct {
+		name     string
+		vdev     zfs.VDevTree
+		errors   int
+		warnings int
+	}{
+		{
+			name: "two mirrors",
+			vdev: zfs.VDevTree{Devices: []zfs.VDevTree{
+				{Type: zfs.VDevTypeMirror, Devices: layoutDisks(gib, "/dev/sda", "/dev/sdb")},
+				{Type: zfs.VDevTypeMirror, Devices: layoutDisks(gib, "/dev/sdc", "/dev/sdd")},
+			}},
+		},
+		{
+			name: "mirror beside single disk",
+			vdev: zfs.VDevTree{Devices: append([]zfs.VDevTree{
+				{Type: zfs.VDevTypeMirror, Devices: layoutDisks(gib, "/dev/sda", "/dev/sdb")}},
+				layoutDisks(gib, "/dev/sdc")...)},
+			warnings: 1,
+		},
+		{
+			name: "mixed raidz parity",
+			vdev: zfs.VDevTree{Devices: []zfs.VDevTree{
+				{Type: zfs.VDevTypeRaidz, Parity: 1, Devices: layoutDisks(gib, "/dev/sda", "/dev/sdb", "/dev/sdc")},
+				{Type: zfs.VDevTypeRaidz, Parity: 2, Devices: layoutDisks(gib, "/dev/sdd", "/dev/sde", "/dev/sdf")},
+			}},
+			warnings: 1,
+		},
+		{
+			name: "mismatched device sizes",
+			vdev: zfs.VDevTree{Devices: []zfs.VDevTree{
+				{Type: zfs.VDevTypeMirror, Devices: append(layoutDisks(gib, "/dev/sda"),
+					layoutDisks(2*gib, "/dev/sdb")...)},
+			}},
+			warnings: 1,
+		},
+		{
+			name: "duplicate device",
+			vdev: zfs.VDevTree{
+				Devices: []zfs.VDevTree{
+					{Type: zfs.VDevTypeMirror, Devices: layoutDisks(gib, "/dev/sda", "/dev/sdb")}},
+				Spares: layoutDisks(gib, "/dev/sdb"),
+			},
+			errors: 1,
+		},
+		{
+			name: "single disk special beside mirror",
+			vdev: zfs.VDevTree{Devices: []zfs.VDevTree{
+				{Type: zfs.VDevTypeMirror, Devices: layoutDisks(gib, "/dev/sda", "/dev/sdb")},
+				{Type: zfs.VDevTypeDisk, Path: "/dev/nvme0n1", Size: gib,
+					AllocBias: zfs.VDevAllocBiasSpecial},
+			}},
+			warnings: 1,
+		},
+		{
+			name:   "no data devices",
+			vdev:   zfs.VDevTree{L2Cache: layoutDisks(gib, "/dev/sda")},
+			errors: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := zfs.ValidateVDevTree(tt.vdev)
+			if len(v.Errors) != tt.errors || len(v.Warnings) != tt.warnings {
+				t.Errorf("ValidateVDevTree() errors = %v, warnings = %v, want %d errors and %d warnings",
+					v.Errors, v.Warnings, tt.errors, tt.warnings)
+			}
+			if (v.Err() != nil) != (tt.errors > 0) {
+				t.Errorf("ValidateVDevTree().Err() = %v", v.Err())
+			}
+		})
+	}
+}
+
+func TestValidateVDevTreeReplicationLabel(t *testing.T) {
+	// special device first, so mirror is second data device but third
+	// top level device
+	vdev := zfs.VDevTree{Devices: []zfs.VDevTree{
+		{Type: zfs.VDevTypeDisk, Path: "/dev/nvme0n1", Size: gib,
+			AllocBias: zfs.VDevAllocBiasSpecial},
+		{Type: zfs.VDevTypeDisk, Path: "/dev/sda", Size: gib},
+		{Type: zfs.VDevTypeMirror, Devices: layoutDisks(gib, "/dev/sdb", "/dev/sdc")},
+	}}
+	v := zfs.ValidateVDevTree(vdev)
+	for _, w := range v.Warnings {
+		if w.Device == "mirror-2" {
+			return
+		}
+	}
+	t.Errorf("ValidateVDevTree() warnings = %v, want mismatched replication of mirror-2",
+		v.Warnings)
+}
+
+func TestVDevTreeCapacity(t *testing.T) {
+	vdev := zfs.VDevTree{Devices: []zfs.VDevTree{
+		{Type: zfs.VDevTypeRaidz, Parity: 2,
+			Devices: layoutDisks(gib, "/dev/sda", "/dev/sdb", "/dev/sdc", "/dev/sdd", "/dev/sde", "/dev/sdf")},
+		{Type: zfs.VDevTypeMirror, AllocBias: zfs.VDevAllocBiasSpecial,
+			Devices: layoutDisks(gib, "/dev/nvme0n1", "/dev/nvme1n1")},
+	}}
+	c, err := zfs.VDevTreeCapacity(vdev)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if c.Raw != 6*gib || c.Usable != 4*gib || c.Special != gib ||
+		c.FaultTolerance != 1 {
+		t.Errorf("VDevTreeCapacity() = %+v", c)
+	}
+
+	vdev.Devices[0].Devices[0].Size = 0
+	if _, err = zfs.VDevTreeCapacity(vdev); err == nil {
+		t.Error("VDevTreeCapacity() should fail on device with unknown size")
+	}
+}
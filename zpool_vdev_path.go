@@ -0,0 +1,123 @@
+package zfs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+)
+
+// VDevPathScheme naming scheme of leaf device paths
+type VDevPathScheme string
+
+// Device path naming schemes
+const (
+	VDevPathAsIs   VDevPathScheme = ""        // VDevPathAsIs keep paths as specified
+	VDevPathByID                  = "by-id"   // VDevPathByID /dev/disk/by-id paths
+	VDevPathByPath                = "by-path" // VDevPathByPath /dev/disk/by-path paths
+)
+
+// devDiskDir directory with persistent device names maintained by udev
+var devDiskDir = "/dev/disk"
+
+// Aliases returns all known names of leaf disk device, canonical device
+// path (e.g. /dev/sda) followed by its persistent names under /dev/disk.
+// Missing or unreadable /dev/disk (e.g. in containers without udev) and its
+// scheme directories just don't add any names.
+func (vdev VDevTree) Aliases() (aliases []string, err error) {
+	if vdev.Type != VDevTypeDisk || len(vdev.Path) == 0 {
+		return
+	}
+	var dev string
+	if dev, err = filepath.EvalSymlinks(vdev.Path); err != nil {
+		return
+	}
+	aliases = append(aliases, dev)
+	schemes, rerr := ioutil.ReadDir(devDiskDir)
+	if rerr != nil {
+		return
+	}
+	for _, scheme := range schemes {
+		links, lerr := deviceLinks(filepath.Join(devDiskDir, scheme.Name()), dev)
+		if lerr != nil {
+			continue
+		}
+		aliases = append(aliases, links...)
+	}
+	return
+}
+
+// deviceLinks returns sorted links in dir pointing to device dev
+func deviceLinks(dir, dev string) (links []string, err error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		link := filepath.Join(dir, e.Name())
+		// skip dangling links of removed devices
+		if target, lerr := filepath.EvalSymlinks(link); lerr == nil && target == dev {
+			links = append(links, link)
+		}
+	}
+	sort.Strings(links)
+	return
+}
+
+// NormalizeVDevPaths returns copy of vdev tree with paths of all leaf disk
+// devices replaced by persistent names of given scheme, so pool survives
+// device renames after reboot (e.g. /dev/sdb to /dev/disk/by-id/ata-...).
+// Files and devices already named by given scheme are left as they are.
+func NormalizeVDevPaths(vdev VDevTree, scheme VDevPathScheme) (normalized VDevTree, err error) {
+	normalized = vdev
+	if normalized.Devices, err = normalizeVDevPaths(vdev.Devices, scheme); err != nil {
+		return
+	}
+	if normalized.Spares, err = normalizeVDevPaths(vdev.Spares, scheme); err != nil {
+		return
+	}
+	if normalized.L2Cache, err = normalizeVDevPaths(vdev.L2Cache, scheme); err != nil {
+		return
+	}
+	if vdev.Logs != nil {
+		var logs VDevTree
+		if logs, err = NormalizeVDevPaths(*vdev.Logs, scheme); err != nil {
+			return
+		}
+		normalized.Logs = &logs
+	}
+	if scheme == VDevPathAsIs || vdev.Type != VDevTypeDisk || len(vdev.Path) == 0 {
+		return
+	}
+	dir := filepath.Join(devDiskDir, string(scheme))
+	if filepath.Dir(vdev.Path) == dir {
+		return
+	}
+	dev, err := filepath.EvalSymlinks(vdev.Path)
+	if err != nil {
+		return
+	}
+	links, err := deviceLinks(dir, dev)
+	if err != nil {
+		return
+	}
+	if len(links) == 0 {
+		err = fmt.Errorf("No %s name for device %s", scheme, vdev.Path)
+		return
+	}
+	normalized.Path = links[0]
+	return
+}
+
+func normalizeVDevPaths(vdevs []VDevTree, scheme VDevPathScheme) (normalized []VDevTree, err error) {
+	if vdevs == nil {
+		return
+	}
+	normalized = make([]VDevTree, len(vdevs))
+	for i, vdev := range vdevs {
+		if normalized[i], err = NormalizeVDevPaths(vdev, scheme); err != nil {
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,106 @@
+package zfs_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	zfs "github.com/bicomsystems/go-libzfs"
+)
+
+func TestNormalizeVDevPaths(t *testing.T) {
+	logs := zfs.VDevTree{Type: zfs.VDevTypeFile, Path: "/tmp/zfs_log"}
+	vdev := zfs.VDevTree{
+		Devices: []zfs.VDevTree{
+			{Type: zfs.VDevTypeMirror, Devices: []zfs.VDevTree{
+				{Type: zfs.VDevTypeFile, Path: "/tmp/zfs_a"},
+				{Type: zfs.VDevTypeFile, Path: "/tmp/zfs_b"},
+			}},
+		},
+		Logs: &logs,
+	}
+	normalized, err := zfs.NormalizeVDevPaths(vdev, zfs.VDevPathByID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !reflect.DeepEqual(normalized, vdev) {
+		t.Errorf("NormalizeVDevPaths() = %+v, files should be left as they are", normalized)
+	}
+	if normalized.Logs == vdev.Logs {
+		t.Error("NormalizeVDevPaths() should not share logs with original tree")
+	}
+
+	disk := zfs.VDevTree{Devices: []zfs.VDevTree{
+		{Type: zfs.VDevTypeDisk, Path: "/dev/zfs_test_missing"}}}
+	if normalized, err = zfs.NormalizeVDevPaths(disk, zfs.VDevPathAsIs); err != nil ||
+		normalized.Devices[0].Path != disk.Devices[0].Path {
+		t.Errorf("NormalizeVDevPaths() = %+v, %v, path should be kept", normalized, err)
+	}
+	if _, err = zfs.NormalizeVDevPaths(disk, zfs.VDevPathByID); err == nil {
+		t.Error("NormalizeVDevPaths() should fail on missing device")
+	}
+}
+
+func TestVDevTreeAliases(t *testing.T) {
+	file := zfs.VDevTree{Type: zfs.VDevTypeFile, Path: "/tmp/zfs_a"}
+	if aliases, err := file.Aliases(); err != nil || len(aliases) != 0 {
+		t.Errorf("Aliases() = %v, %v, files have no aliases", aliases, err)
+	}
+}
+
+// fakeDevDisk creates device file and /dev/disk like directory with by-id
+// link to it and by-path scheme which can not be read
+func fakeDevDisk(t *testing.T) (dir, dev, link string) {
+	dir, err := ioutil.TempDir("", "zfs_dev_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir, err = filepath.EvalSymlinks(dir); err != nil {
+		t.Fatal(err)
+	}
+	dev = filepath.Join(dir, "sdz")
+	if err = ioutil.WriteFile(dev, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.MkdirAll(filepath.Join(dir, "disk", "by-id"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	link = filepath.Join(dir, "disk", "by-id", "ata-ZFS_TEST_1")
+	if err = os.Symlink(dev, link); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(dir, "disk", "by-path"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestVDevTreeAliasesDevDisk(t *testing.T) {
+	dir, dev, link := fakeDevDisk(t)
+	defer os.RemoveAll(dir)
+	disk := zfs.VDevTree{Type: zfs.VDevTypeDisk, Path: dev}
+
+	restore := zfs.SetDevDiskDir(filepath.Join(dir, "disk"))
+	aliases, err := disk.Aliases()
+	restore()
+	if err != nil || !reflect.DeepEqual(aliases, []string{dev, link}) {
+		t.Errorf("Aliases() = %v, %v, want %v", aliases, err, []string{dev, link})
+	}
+	restore = zfs.SetDevDiskDir(filepath.Join(dir, "disk"))
+	normalized, err := zfs.NormalizeVDevPaths(zfs.VDevTree{
+		Devices: []zfs.VDevTree{disk}}, zfs.VDevPathByID)
+	restore()
+	if err != nil || normalized.Devices[0].Path != link {
+		t.Errorf("NormalizeVDevPaths() = %+v, %v, want path %s", normalized, err, link)
+	}
+
+	// no /dev/disk at all
+	restore = zfs.SetDevDiskDir(filepath.Join(dir, "missing"))
+	defer restore()
+	if aliases, err = disk.Aliases(); err != nil || !reflect.DeepEqual(aliases, []string{dev}) {
+		t.Errorf("Aliases() = %v, %v, want only %s", aliases, err, dev)
+	}
+}
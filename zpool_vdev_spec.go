@@ -0,0 +1,325 @@
+package zfs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Device classes of zpool vdev specification
+const (
+	vdevSpecLog     = "log"
+	vdevSpecSpecial = "special"
+	vdevSpecDedup   = "dedup"
+	vdevSpecCache   = "cache"
+	vdevSpecSpare   = "spare"
+)
+
+// ParseVDevSpec parses vdev specification in format used by zpool create
+// command, e.g. "mirror sda sdb mirror sdc sdd log mirror nvme0 nvme1 cache
+// nvme2 spare sde", to VDevTree as expected by PoolCreate. Log, special and
+// dedup devices are returned as top level devices with AllocBias set. Bare
+// device names and paths under /dev are disks, other absolute paths files.
+//
+// Supported groups are mirror, raidz[1-3] and
+// draid[<parity>][:<data>d][:<children>c][:<spares>s].
+func ParseVDevSpec(spec string) (vdev VDevTree, err error) {
+	tokens := strings.Fields(spec)
+	if len(tokens) == 0 {
+		err = fmt.Errorf("Invalid vdev specification: empty specification")
+		return
+	}
+	var class string
+	var group *VDevTree
+	classDevices := 0
+	closeGroup := func() (err error) {
+		if group == nil {
+			return
+		}
+		if err = checkSpecGroup(*group); err != nil {
+			return
+		}
+		vdev.Devices = append(vdev.Devices, *group)
+		group = nil
+		return
+	}
+	closeClass := func() (err error) {
+		if err = closeGroup(); err != nil {
+			return
+		}
+		if len(class) > 0 && classDevices == 0 {
+			err = fmt.Errorf(
+				"Invalid vdev specification: '%s' requires at least one device", class)
+		}
+		return
+	}
+	for i, token := range tokens {
+		switch token {
+		case vdevSpecLog, vdevSpecSpecial, vdevSpecDedup, vdevSpecCache, vdevSpecSpare:
+			if err = closeClass(); err != nil {
+				return
+			}
+			class = token
+			classDevices = 0
+			continue
+		}
+		var g VDevTree
+		var isGroup bool
+		if g, isGroup, err = parseSpecGroup(token); err != nil {
+			return
+		}
+		if isGroup {
+			if class == vdevSpecCache || class == vdevSpecSpare {
+				err = fmt.Errorf(
+					"Invalid vdev specification: %s devices can not be '%s' (token %d)",
+					class, token, i+1)
+				return
+			}
+			if err = closeGroup(); err != nil {
+				return
+			}
+			g.AllocBias = specAllocBias(class)
+			group = &g
+			classDevices++
+			continue
+		}
+		leaf := parseSpecLeaf(token)
+		switch {
+		case group != nil:
+			group.Devices = append(group.Devices, leaf)
+		case class == vdevSpecCache:
+			vdev.L2Cache = append(vdev.L2Cache, leaf)
+		case class == vdevSpecSpare:
+			vdev.Spares = append(vdev.Spares, leaf)
+		default:
+			leaf.AllocBias = specAllocBias(class)
+			vdev.Devices = append(vdev.Devices, leaf)
+		}
+		classDevices++
+	}
+	err = closeClass()
+	return
+}
+
+func specAllocBias(class string) VDevAllocBias {
+	switch class {
+	case vdevSpecLog:
+		return VDevAllocBiasLog
+	case vdevSpecSpecial:
+		return VDevAllocBiasSpecial
+	case vdevSpecDedup:
+		return VDevAllocBiasDedup
+	}
+	return VDevAllocBiasNone
+}
+
+func parseSpecLeaf(token string) VDevTree {
+	if strings.HasPrefix(token, "/") && !strings.HasPrefix(token, "/dev/") {
+		return VDevTree{Type: VDevTypeFile, Path: token}
+	}
+	return VDevTree{Type: VDevTypeDisk, Path: token}
+}
+
+// parseSpecGroup parses group keyword (mirror, raidz2, draid2:4d:1s ...),
+// returns isGroup false if token is device
+func parseSpecGroup(token string) (vdev VDevTree, isGroup bool, err error) {
+	switch {
+	case token == VDevTypeMirror:
+		vdev.Type = VDevTypeMirror
+	case strings.HasPrefix(token, VDevTypeRaidz):
+		vdev.Type = VDevTypeRaidz
+		vdev.Parity, err = parseSpecParity(token, token[len(VDevTypeRaidz):])
+	case strings.HasPrefix(token, VDevTypeDraid):
+		vdev.Type = VDevTypeDraid
+		err = parseSpecDraid(token, &vdev)
+	default:
+		return
+	}
+	isGroup = true
+	return
+}
+
+func parseSpecParity(token, s string) (parity uint, err error) {
+	if len(s) == 0 {
+		return 1, nil
+	}
+	p, perr := strconv.ParseUint(s, 10, 32)
+	if perr != nil || p < 1 || p > vdevMaxParity {
+		err = fmt.Errorf("Invalid vdev specification: invalid parity in '%s'", token)
+		return
+	}
+	parity = uint(p)
+	return
+}
+
+func parseSpecDraid(token string, vdev *VDevTree) (err error) {
+	parts := strings.Split(token[len(VDevTypeDraid):], ":")
+	if vdev.Parity, err = parseSpecParity(token, parts[0]); err != nil {
+		return
+	}
+	for _, part := range parts[1:] {
+		if len(part) < 2 {
+			return fmt.Errorf("Invalid vdev specification: invalid dRAID option '%s' in '%s'",
+				part, token)
+		}
+		n, perr := strconv.ParseUint(part[:len(part)-1], 10, 32)
+		if perr != nil {
+			return fmt.Errorf("Invalid vdev specification: invalid dRAID option '%s' in '%s'",
+				part, token)
+		}
+		switch part[len(part)-1] {
+		case 'd':
+			vdev.DraidData = uint(n)
+		case 'c':
+			vdev.DraidChildren = uint(n)
+		case 's':
+			vdev.DraidSpares = uint(n)
+		default:
+			return fmt.Errorf("Invalid vdev specification: invalid dRAID option '%s' in '%s'",
+				part, token)
+		}
+	}
+	return
+}
+
+func checkSpecGroup(vdev VDevTree) (err error) {
+	_, mindevs, maxdevs := vdev.isGrouping()
+	if len(vdev.Devices) < mindevs || len(vdev.Devices) > maxdevs {
+		err = fmt.Errorf(
+			"Invalid vdev specification: %s requires %d to %d devices, but %d are given",
+			vdev.Type, mindevs, maxdevs, len(vdev.Devices))
+		return
+	}
+	if vdev.Type == VDevTypeDraid {
+		_, _, err = vdev.draidLayout()
+	}
+	return
+}
+
+// Spec formats vdev tree as zpool vdev specification, inverse of
+// ParseVDevSpec. Devices being replaced or substituted by spare are
+// formatted as original device, holes and missing devices are skipped, as
+// well as distributed spares of dRAID devices.
+func (vdev VDevTree) Spec() (spec string, err error) {
+	var tokens []string
+	if vdev.Type != VDevTypeRoot && len(vdev.Type) > 0 {
+		if tokens, err = vdev.specTokens(); err != nil {
+			return
+		}
+		spec = strings.Join(tokens, " ")
+		return
+	}
+	classes := make(map[string][]string)
+	addClass := func(class string, v VDevTree) (err error) {
+		var t []string
+		if t, err = v.specTokens(); err == nil {
+			classes[class] = append(classes[class], t...)
+		}
+		return
+	}
+	for _, d := range vdev.Devices {
+		class := string(d.AllocBias)
+		if d.Type == VDevTypeLog {
+			for _, l := range d.Devices {
+				if err = addClass(vdevSpecLog, l); err != nil {
+					return
+				}
+			}
+			continue
+		}
+		if err = addClass(class, d); err != nil {
+			return
+		}
+	}
+	if vdev.Logs != nil {
+		if err = addClass(vdevSpecLog, *vdev.Logs); err != nil {
+			return
+		}
+	}
+	for _, d := range vdev.L2Cache {
+		if err = addClass(vdevSpecCache, d); err != nil {
+			return
+		}
+	}
+	for _, d := range vdev.Spares {
+		if d.Type == VDevTypeDraidSpare {
+			continue // implied by spares of dRAID device
+		}
+		if err = addClass(vdevSpecSpare, d); err != nil {
+			return
+		}
+	}
+	tokens = classes[""]
+	for _, class := range []string{vdevSpecSpecial, vdevSpecDedup, vdevSpecLog,
+		vdevSpecCache, vdevSpecSpare} {
+		if len(classes[class]) > 0 {
+			tokens = append(append(tokens, class), classes[class]...)
+		}
+	}
+	if len(tokens) == 0 {
+		err = fmt.Errorf("Invalid vdev specification: no devices")
+		return
+	}
+	spec = strings.Join(tokens, " ")
+	return
+}
+
+func (vdev *VDevTree) specTokens() (tokens []string, err error) {
+	switch vdev.Type {
+	case VDevTypeDisk, VDevTypeFile:
+		if len(vdev.Path) == 0 {
+			err = fmt.Errorf("Invalid vdev specification: %s device without path",
+				vdev.Type)
+			return
+		}
+		tokens = []string{vdev.Path}
+		return
+	case VDevTypeHole, VDevTypeMissing:
+		return
+	case VDevTypeReplacing, VDevTypeSpare:
+		if len(vdev.Devices) == 0 {
+			err = fmt.Errorf("Invalid vdev specification: empty %s device", vdev.Type)
+			return
+		}
+		return vdev.Devices[0].specTokens()
+	case VDevTypeMirror:
+		tokens = []string{VDevTypeMirror}
+	case VDevTypeRaidz:
+		tokens = []string{VDevTypeRaidz}
+		if vdev.Parity > 1 {
+			tokens[0] = fmt.Sprintf("%s%d", VDevTypeRaidz, vdev.Parity)
+		}
+	case VDevTypeDraid:
+		tokens = []string{vdev.draidSpec()}
+	default:
+		err = fmt.Errorf("Invalid vdev specification: unsupported device type '%s'",
+			vdev.Type)
+		return
+	}
+	for _, d := range vdev.Devices {
+		var t []string
+		if t, err = d.specTokens(); err != nil {
+			return
+		}
+		tokens = append(tokens, t...)
+	}
+	return
+}
+
+func (vdev *VDevTree) draidSpec() string {
+	parity := vdev.Parity
+	if parity == 0 {
+		parity = 1
+	}
+	spec := fmt.Sprintf("%s%d", VDevTypeDraid, parity)
+	if vdev.DraidData > 0 {
+		spec += fmt.Sprintf(":%dd", vdev.DraidData)
+	}
+	if vdev.DraidChildren > 0 {
+		spec += fmt.Sprintf(":%dc", vdev.DraidChildren)
+	}
+	if vdev.DraidSpares > 0 {
+		spec += fmt.Sprintf(":%ds", vdev.DraidSpares)
+	}
+	return spec
+}
@@ -0,0 +1,116 @@
+package zfs_test
+
+import (
+	"reflect"
+	"testing"
+
+	zfs "github.com/bicomsystems/go-libzfs"
+)
+
+func TestParseVDevSpec(t *testing.T) {
+	spec := "mirror sda sdb mirror sdc sdd log mirror nvme0 nvme1 cache nvme2 spare sde"
+	want := zfs.VDevTree{
+		Devices: []zfs.VDevTree{
+			{Type: zfs.VDevTypeMirror, Devices: layoutDisks(0, "sda", "sdb")},
+			{Type: zfs.VDevTypeMirror, Devices: layoutDisks(0, "sdc", "sdd")},
+			{Type: zfs.VDevTypeMirror, AllocBias: zfs.VDevAllocBiasLog,
+				Devices: layoutDisks(0, "nvme0", "nvme1")},
+		},
+		L2Cache: layoutDisks(0, "nvme2"),
+		Spares:  layoutDisks(0, "sde"),
+	}
+	vdev, err := zfs.ParseVDevSpec(spec)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !reflect.DeepEqual(vdev, want) {
+		t.Errorf("ParseVDevSpec() = %+v, want %+v", vdev, want)
+	}
+	if s, err := vdev.Spec(); err != nil || s != spec {
+		t.Errorf("Spec() = %q, %v, want %q", s, err, spec)
+	}
+}
+
+func TestVDevSpecRoundTrip(t *testing.T) {
+	specs := []string{
+		"/tmp/a /tmp/b",
+		"raidz2 sda sdb sdc sdd special mirror nvme0 nvme1 dedup nvme2",
+		"draid2:4d:1s sda sdb sdc sdd sde sdf sdg sdh log /dev/nvme0n1",
+		"raidz3 sda sdb sdc sdd sde spare sdf sdg",
+	}
+	for _, spec := range specs {
+		vdev, err := zfs.ParseVDevSpec(spec)
+		if err != nil {
+			t.Errorf("ParseVDevSpec(%q) error: %v", spec, err)
+			continue
+		}
+		if s, err := vdev.Spec(); err != nil || s != spec {
+			t.Errorf("Spec() = %q, %v, want %q", s, err, spec)
+		}
+	}
+
+	vdev, err := zfs.ParseVDevSpec("/tmp/a /dev/sda")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if vdev.Devices[0].Type != zfs.VDevTypeFile || vdev.Devices[1].Type != zfs.VDevTypeDisk {
+		t.Errorf("ParseVDevSpec() device types = %s, %s", vdev.Devices[0].Type,
+			vdev.Devices[1].Type)
+	}
+}
+
+// dRAID tree as read back from pool by VDevTree, with distributed spare
+// listed among spares and activated in place of one of devices
+func TestVDevSpecDraidRoundTrip(t *testing.T) {
+	files := func(paths ...string) (vdevs []zfs.VDevTree) {
+		for _, p := range paths {
+			vdevs = append(vdevs, zfs.VDevTree{Type: zfs.VDevTypeFile, Path: p})
+		}
+		return
+	}
+	dspare := zfs.VDevTree{Type: zfs.VDevTypeDraidSpare, Name: "draid1-0-0"}
+	draid := zfs.VDevTree{Type: zfs.VDevTypeDraid, Parity: 1, DraidData: 2,
+		DraidChildren: 4, DraidSpares: 1, DraidGroups: 1,
+		Devices: files("/tmp/a", "/tmp/b", "/tmp/c", "/tmp/d")}
+	draid.Devices[1] = zfs.VDevTree{Type: zfs.VDevTypeSpare,
+		Devices: append(files("/tmp/b"), dspare)}
+	vdev := zfs.VDevTree{Type: zfs.VDevTypeRoot,
+		Devices: []zfs.VDevTree{draid},
+		Spares:  append([]zfs.VDevTree{dspare}, files("/tmp/e")...)}
+
+	spec := "draid1:2d:4c:1s /tmp/a /tmp/b /tmp/c /tmp/d spare /tmp/e"
+	s, err := vdev.Spec()
+	if err != nil || s != spec {
+		t.Errorf("Spec() = %q, %v, want %q", s, err, spec)
+		return
+	}
+	parsed, err := zfs.ParseVDevSpec(s)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if s, err = parsed.Spec(); err != nil || s != spec {
+		t.Errorf("Spec() of parsed = %q, %v, want %q", s, err, spec)
+	}
+}
+
+func TestParseVDevSpecErrors(t *testing.T) {
+	specs := []string{
+		"",
+		"mirror sda",
+		"raidz2 sda sdb",
+		"raidz4 sda sdb sdc sdd sde",
+		"draid1:3x sda sdb sdc",
+		"draid1:5c sda sdb sdc",
+		"sda log",
+		"sda cache mirror sdb sdc",
+		"sda spare raidz sdb sdc",
+	}
+	for _, spec := range specs {
+		if vdev, err := zfs.ParseVDevSpec(spec); err == nil {
+			t.Errorf("ParseVDevSpec(%q) = %+v, expected error", spec, vdev)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package zfs
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+// SkipVDev returned from VDevWalkFunc skips children of current device
+var SkipVDev = errors.New("skip this vdev")
+
+// VDevWalkFunc is called by Walk for every device in the tree, parent is
+// nil for the device Walk is called on
+type VDevWalkFunc func(vdev, parent *VDevTree) error
+
+// Walk calls fn for device and all its descendants including Logs, L2Cache
+// and Spares, in depth first order. Devices are passed by pointer into the
+// tree so fn can modify them. Walking stops on first error returned by fn
+// other than SkipVDev, and the error is returned.
+func (vdev *VDevTree) Walk(fn VDevWalkFunc) (err error) {
+	err = vdev.walk(nil, fn)
+	if err == SkipVDev {
+		err = nil
+	}
+	return
+}
+
+func (vdev *VDevTree) walk(parent *VDevTree, fn VDevWalkFunc) (err error) {
+	if err = fn(vdev, parent); err != nil {
+		if err == SkipVDev {
+			err = nil
+		}
+		return
+	}
+	for _, d := range vdev.children() {
+		if err = d.walk(vdev, fn); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// children returns pointers to all direct children of device
+func (vdev *VDevTree) children() (children []*VDevTree) {
+	for i := range vdev.Devices {
+		children = append(children, &vdev.Devices[i])
+	}
+	if vdev.Logs != nil {
+		children = append(children, vdev.Logs)
+	}
+	for i := range vdev.L2Cache {
+		children = append(children, &vdev.L2Cache[i])
+	}
+	for i := range vdev.Spares {
+		children = append(children, &vdev.Spares[i])
+	}
+	return
+}
+
+// find returns first device in the tree matching condition, nil if none does
+func (vdev *VDevTree) find(match func(v *VDevTree) bool) (found *VDevTree) {
+	vdev.Walk(func(v, parent *VDevTree) error {
+		if match(v) {
+			found = v
+			return errors.New("found")
+		}
+		return nil
+	})
+	return
+}
+
+// FindByGUID returns device with given GUID, nil if there is no such device
+func (vdev *VDevTree) FindByGUID(guid uint64) *VDevTree {
+	return vdev.find(func(v *VDevTree) bool {
+		return v.GUID == guid
+	})
+}
+
+// FindByPath returns leaf device with given path, nil if there is no such
+// device. Short disk names (sda) and symbolic links (/dev/disk/by-id/...)
+// match device they resolve to.
+func (vdev *VDevTree) FindByPath(path string) *VDevTree {
+	if !strings.HasPrefix(path, "/") {
+		path = filepath.Join("/dev", path)
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		resolved = ""
+	}
+	return vdev.find(func(v *VDevTree) bool {
+		if len(v.Path) == 0 || len(v.Devices) > 0 {
+			return false
+		}
+		if v.Path == path {
+			return true
+		}
+		if len(resolved) == 0 {
+			return false
+		}
+		vpath, err := filepath.EvalSymlinks(v.Path)
+		return err == nil && vpath == resolved
+	})
+}
+
+// Leaves returns all leaf devices in the tree, including logs, cache and
+// spare devices
+func (vdev *VDevTree) Leaves() (leaves []*VDevTree) {
+	vdev.Walk(func(v, parent *VDevTree) error {
+		if len(v.Devices) == 0 && v.Type != VDevTypeRoot &&
+			v.Type != VDevTypeHole && v.Type != VDevTypeMissing {
+			leaves = append(leaves, v)
+		}
+		return nil
+	})
+	return
+}
+
+// Parent returns parent of device child found in this tree (e.g. by
+// FindByGUID), nil if child is not in the tree or it is its root
+func (vdev *VDevTree) Parent(child *VDevTree) (parent *VDevTree) {
+	vdev.find(func(v *VDevTree) bool {
+		for _, c := range v.children() {
+			if c == child {
+				parent = v
+				return true
+			}
+		}
+		return false
+	})
+	return
+}
+
+// TopLevel returns top level device (e.g. mirror, raidz, log or spare)
+// containing device child, child itself if it is top level device, or nil
+// if child is not in the tree or it is its root
+func (vdev *VDevTree) TopLevel(child *VDevTree) *VDevTree {
+	for _, top := range vdev.children() {
+		if top.find(func(v *VDevTree) bool { return v == child }) != nil {
+			return top
+		}
+	}
+	return nil
+}
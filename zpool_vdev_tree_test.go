@@ -0,0 +1,88 @@
+package zfs_test
+
+import (
+	"testing"
+
+	zfs "github.com/bicomsystems/go-libzfs"
+)
+
+func navigationTree() zfs.VDevTree {
+	logs := zfs.VDevTree{Type: zfs.VDevTypeMirror, GUID: 5, Devices: []zfs.VDevTree{
+		{Type: zfs.VDevTypeFile, Path: "/tmp/log0", GUID: 6},
+		{Type: zfs.VDevTypeFile, Path: "/tmp/log1", GUID: 7},
+	}}
+	return zfs.VDevTree{Type: zfs.VDevTypeRoot, GUID: 1,
+		Devices: []zfs.VDevTree{
+			{Type: zfs.VDevTypeMirror, GUID: 2, Devices: []zfs.VDevTree{
+				{Type: zfs.VDevTypeFile, Path: "/tmp/a", GUID: 3},
+				{Type: zfs.VDevTypeFile, Path: "/tmp/b", GUID: 4},
+			}},
+		},
+		Logs:    &logs,
+		L2Cache: []zfs.VDevTree{{Type: zfs.VDevTypeFile, Path: "/tmp/cache", GUID: 8}},
+		Spares:  []zfs.VDevTree{{Type: zfs.VDevTypeFile, Path: "/tmp/spare", GUID: 9}},
+	}
+}
+
+func TestVDevTreeWalk(t *testing.T) {
+	vdev := navigationTree()
+	var guids []uint64
+	err := vdev.Walk(func(v, parent *zfs.VDevTree) error {
+		guids = append(guids, v.GUID)
+		if v.Type == zfs.VDevTypeMirror && v.GUID == 5 {
+			return zfs.SkipVDev
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	want := []uint64{1, 2, 3, 4, 5, 8, 9}
+	if len(guids) != len(want) {
+		t.Errorf("Walk() visited %v, want %v", guids, want)
+		return
+	}
+	for i := range want {
+		if guids[i] != want[i] {
+			t.Errorf("Walk() visited %v, want %v", guids, want)
+			return
+		}
+	}
+
+	if leaves := vdev.Leaves(); len(leaves) != 6 {
+		t.Errorf("Leaves() returned %d devices, want 6", len(leaves))
+	}
+}
+
+func TestVDevTreeFind(t *testing.T) {
+	vdev := navigationTree()
+	d := vdev.FindByGUID(7)
+	if d == nil || d.Path != "/tmp/log1" {
+		t.Errorf("FindByGUID(7) = %+v", d)
+		return
+	}
+	if p := vdev.Parent(d); p == nil || p.GUID != 5 {
+		t.Errorf("Parent() = %+v, want log mirror", p)
+	}
+	if top := vdev.TopLevel(d); top != vdev.Logs {
+		t.Errorf("TopLevel() = %+v, want log mirror", top)
+	}
+	if d = vdev.FindByPath("/tmp/b"); d == nil || d.GUID != 4 {
+		t.Errorf("FindByPath(/tmp/b) = %+v", d)
+		return
+	}
+	if top := vdev.TopLevel(d); top == nil || top.GUID != 2 {
+		t.Errorf("TopLevel() = %+v, want data mirror", top)
+	}
+	if d = vdev.FindByPath("/tmp/spare"); d == nil || vdev.TopLevel(d) != d {
+		t.Errorf("Spare should be its own top level device")
+	}
+	d.Path = "/tmp/spare2"
+	if vdev.Spares[0].Path != "/tmp/spare2" {
+		t.Error("FindByPath() should return device in the tree")
+	}
+	if vdev.FindByGUID(42) != nil || vdev.FindByPath("/tmp/none") != nil ||
+		vdev.Parent(&vdev) != nil || vdev.TopLevel(&vdev) != nil {
+		t.Error("Expected nil for devices not in the tree")
+	}
+}
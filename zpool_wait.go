@@ -0,0 +1,211 @@
+package zfs
+
+// #include <stdlib.h>
+// #include <libzfs.h>
+// #include "common.h"
+// #include "zpool.h"
+// #include "zfs.h"
+import "C"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// PoolWaitActivity background pool activity Wait can wait for
+type PoolWaitActivity int
+
+// Pool activities
+const (
+	PoolWaitCheckpointDiscard PoolWaitActivity = C.ZPOOL_WAIT_CKPT_DISCARD // discard of pool checkpoint
+	PoolWaitFree              PoolWaitActivity = C.ZPOOL_WAIT_FREE         // freeing of destroyed datasets
+	PoolWaitInitialize        PoolWaitActivity = C.ZPOOL_WAIT_INITIALIZE   // device initialization
+	PoolWaitReplace           PoolWaitActivity = C.ZPOOL_WAIT_REPLACE      // device replacement
+	PoolWaitRemove            PoolWaitActivity = C.ZPOOL_WAIT_REMOVE       // device removal
+	PoolWaitResilver          PoolWaitActivity = C.ZPOOL_WAIT_RESILVER     // resilver
+	PoolWaitScrub             PoolWaitActivity = C.ZPOOL_WAIT_SCRUB        // scrub
+	PoolWaitTrim              PoolWaitActivity = C.ZPOOL_WAIT_TRIM         // device trim
+)
+
+// waitProgressInterval how often Wait reports progress
+const waitProgressInterval = time.Second
+
+// PoolWaitProgress progress of background pool activity in bytes
+type PoolWaitProgress struct {
+	Activity  PoolWaitActivity
+	Done      uint64 // bytes processed, 0 if not known (free, checkpoint discard)
+	Remaining uint64 // bytes left to process
+}
+
+// Percent returns percentage of activity completed
+func (p PoolWaitProgress) Percent() float64 {
+	if p.Done+p.Remaining == 0 {
+		return 100
+	}
+	return float64(p.Done) * 100 / float64(p.Done+p.Remaining)
+}
+
+// Wait blocks until background pool activity (e.g. scrub, resilver) is
+// completed, or ctx is done. If progress is not nil it is called every
+// second with progress of the activity. Wait returns immediately if the
+// activity is not in progress. Waiting is done on its own libzfs and pool
+// handle, so if ctx is done before activity completes it is safe to close
+// pool. Concurrent Wait calls for the same pool and activity share one
+// blocked wait, which stays until the activity completes or the pool is
+// exported or destroyed, so Wait calls done early don't pile up.
+func (pool *Pool) Wait(ctx context.Context, activity PoolWaitActivity,
+	progress func(PoolWaitProgress)) (err error) {
+	if pool.list == nil {
+		err = errors.New(msgPoolIsNil)
+		return
+	}
+	name, err := pool.Name()
+	if err != nil {
+		return
+	}
+	call := startPoolWait(name, activity)
+	ticker := time.NewTicker(waitProgressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-call.done:
+			err = call.err
+			return
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if progress == nil {
+				continue
+			}
+			if p, perr := pool.Progress(activity); perr == nil {
+				progress(p)
+			}
+		}
+	}
+}
+
+type poolWaitKey struct {
+	name     string
+	activity PoolWaitActivity
+}
+
+// poolWaitCall blocked wait for pool activity, err is set before done is
+// closed
+type poolWaitCall struct {
+	done chan struct{}
+	err  error
+}
+
+// poolWaits blocked waits in progress, at most one per pool and activity
+var poolWaits = struct {
+	sync.Mutex
+	calls map[poolWaitKey]*poolWaitCall
+}{calls: make(map[poolWaitKey]*poolWaitCall)}
+
+// startPoolWait returns blocked wait in progress for activity of pool with
+// given name, or starts new one if there is none
+func startPoolWait(name string, activity PoolWaitActivity) (call *poolWaitCall) {
+	key := poolWaitKey{name, activity}
+	poolWaits.Lock()
+	defer poolWaits.Unlock()
+	if call = poolWaits.calls[key]; call != nil {
+		return
+	}
+	call = &poolWaitCall{done: make(chan struct{})}
+	poolWaits.calls[key] = call
+	go func() {
+		call.err = poolWait(name, activity)
+		poolWaits.Lock()
+		delete(poolWaits.calls, key)
+		poolWaits.Unlock()
+		close(call.done)
+	}()
+	return
+}
+
+func poolWait(name string, activity PoolWaitActivity) (err error) {
+	csName := C.CString(name)
+	defer C.free(unsafe.Pointer(csName))
+	var missing C.boolean_t
+	var errbuf [1024]C.char
+	switch r := C.do_zpool_wait(csName, C.zpool_wait_activity_t(activity),
+		&missing, &errbuf[0], C.size_t(len(errbuf))); r {
+	case 0:
+	case -1:
+		err = errors.New(C.GoString(&errbuf[0]))
+		return
+	default:
+		err = fmt.Errorf("Pool wait failed: %s", syscall.Errno(r))
+		return
+	}
+	if missing != C.B_FALSE {
+		err = fmt.Errorf("Pool %s was exported or destroyed while waiting", name)
+	}
+	return
+}
+
+// Progress returns current progress of background pool activity
+func (pool *Pool) Progress(activity PoolWaitActivity) (p PoolWaitProgress, err error) {
+	p.Activity = activity
+	if err = pool.RefreshStats(); err != nil {
+		return
+	}
+	switch activity {
+	case PoolWaitFree:
+		var prop Property
+		if prop, err = pool.GetProperty(PoolPropFreeing); err != nil {
+			return
+		}
+		p.Remaining, err = strconv.ParseUint(prop.Value, 10, 64)
+		return
+	case PoolWaitRemove, PoolWaitCheckpointDiscard:
+		var nvroot *C.struct_nvlist
+		config := C.zpool_get_config(pool.list.zph, nil)
+		if config == nil ||
+			C.nvlist_lookup_nvlist(config, C.sZPOOL_CONFIG_VDEV_TREE, &nvroot) != 0 {
+			err = fmt.Errorf("Failed to fetch %s", C.ZPOOL_CONFIG_VDEV_TREE)
+			return
+		}
+		if activity == PoolWaitRemove {
+			if prs := C.get_vdev_removal_stats(nvroot); prs != nil &&
+				prs.prs_state == C.DSS_SCANNING {
+				p.Done = uint64(prs.prs_copied)
+				p.Remaining = uint64(prs.prs_to_copy) - p.Done
+			}
+		} else if pcs := C.get_vdev_checkpoint_stats(nvroot); pcs != nil &&
+			pcs.pcs_state == C.CS_CHECKPOINT_DISCARDING {
+			p.Remaining = uint64(pcs.pcs_space)
+		}
+		return
+	}
+
+	vdevs, err := pool.VDevTree()
+	if err != nil {
+		return
+	}
+	switch activity {
+	case PoolWaitScrub, PoolWaitResilver, PoolWaitReplace:
+		if vdevs.ScanStat.State == uint64(C.DSS_SCANNING) &&
+			vdevs.ScanStat.ToExamine > vdevs.ScanStat.Examined {
+			p.Done = vdevs.ScanStat.Examined
+			p.Remaining = vdevs.ScanStat.ToExamine - p.Done
+		}
+	case PoolWaitInitialize, PoolWaitTrim:
+		for _, leaf := range vdevs.Leaves() {
+			done, est := leaf.Stat.InitializeDone, leaf.Stat.InitializeEst
+			if activity == PoolWaitTrim {
+				done, est = leaf.Stat.TrimDone, leaf.Stat.TrimEst
+			}
+			p.Done += done
+			if est > done {
+				p.Remaining += est - done
+			}
+		}
+	}
+	return
+}